@@ -0,0 +1,108 @@
+package main
+
+import "fmt"
+
+// Context used when the screen has no binding of its own for a key
+const CONTEXT_GLOBAL = "global"
+
+// Gesture sent by remote-control for a plain key press
+const GESTURE_PRESS = "press"
+
+// Bindings maps, for each screen, key names to actions. Gestures other than
+// a plain press are bound as "<key>:<gesture>", e.g. "KEY_OK:long".
+type Bindings map[string]map[string]string
+
+// Built-in bindings, used for every key and screen not set in the
+// configuration.
+var defaultBindings = Bindings{
+	CONTEXT_GLOBAL: withDigits(map[string]string{
+		"KEY_UP":         "nav.up",
+		"KEY_DOWN":       "nav.down",
+		"KEY_LEFT":       "nav.left",
+		"KEY_RIGHT":      "nav.right",
+		"KEY_PAGEUP":     "nav.page_up",
+		"KEY_PAGEDOWN":   "nav.page_down",
+		"KEY_OK":         "nav.ok",
+		"KEY_OK:long":    "explorer.enqueue",
+		"KEY_BACKSPACE":  "nav.back",
+		"KEY_MEDIA":      "explorer.sort",
+		"KEY_INFO":       "explorer.enqueue",
+		"KEY_PROG1":      "explorer.favorite",
+		"KEY_PLAYPAUSE":  "player.toggle",
+		"KEY_STOP":       "player.stop",
+		"KEY_NEXT":       "player.next",
+		"KEY_PREVIOUS":   "player.previous",
+		"KEY_VOLUMEUP":   "volume.up",
+		"KEY_VOLUMEDOWN": "volume.down",
+		"KEY_MUTE":       "volume.mute",
+		// holding a key keeps scrolling or changing the volume
+		"KEY_UP:repeat":         "nav.up",
+		"KEY_DOWN:repeat":       "nav.down",
+		"KEY_PAGEUP:repeat":     "nav.page_up",
+		"KEY_PAGEDOWN:repeat":   "nav.page_down",
+		"KEY_VOLUMEUP:repeat":   "volume.up",
+		"KEY_VOLUMEDOWN:repeat": "volume.down",
+		// the jog dial of the panel changes the volume, except on lists
+		"DIAL_LEFT":  "volume.down",
+		"DIAL_RIGHT": "volume.up",
+		"DIAL_PRESS": "nav.ok",
+		// numbers typed with the digit keys select a track of the queue
+		ENTRY_KEY: "player.jump",
+	}),
+	"explorer": {
+		"DIAL_LEFT":  "nav.up",
+		"DIAL_RIGHT": "nav.down",
+		ENTRY_KEY:    "explorer.select",
+	},
+	"search": {
+		"DIAL_LEFT":  "nav.up",
+		"DIAL_RIGHT": "nav.down",
+		ENTRY_KEY:    "",
+	},
+	"player": {
+		"KEY_LEFT":   "player.previous",
+		"KEY_RIGHT":  "player.next",
+		"DIAL_PRESS": "player.toggle",
+	},
+}
+
+// withDigits binds the digit keys of remotes, keyboards and keypads to the
+// entry, which types a number sent as the ENTRY action of the screen.
+func withDigits(keys map[string]string) map[string]string {
+	for digit := range 10 {
+		for _, key := range []string{"KEY_%d", "KEY_KP%d", "KEY_NUMERIC_%d"} {
+			keys[fmt.Sprintf(key, digit)] = fmt.Sprintf("%s%d", ENTRY_ACTION_PREFIX, digit)
+		}
+	}
+	return keys
+}
+
+// merge returns the default bindings overridden by custom. An empty action
+// unbinds the key.
+func merge(custom Bindings) Bindings {
+	bindings := Bindings{}
+	for _, source := range []Bindings{defaultBindings, custom} {
+		for context, keys := range source {
+			if bindings[context] == nil {
+				bindings[context] = map[string]string{}
+			}
+			for key, action := range keys {
+				bindings[context][key] = action
+			}
+		}
+	}
+	return bindings
+}
+
+// Resolve returns the action bound to the key gesture on the screen,
+// falling back to the global bindings.
+func (b Bindings) Resolve(screen string, key string, gesture string) (string, bool) {
+	if gesture != "" && gesture != GESTURE_PRESS {
+		key += ":" + gesture
+	}
+	if action, ok := b[screen][key]; ok {
+		return action, action != ""
+	}
+	action, ok := b[CONTEXT_GLOBAL][key]
+	return action, ok && action != ""
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"media-player/pkg/queue"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const NOTIFICATION_TOPIC = "notification"
+
+// Actions of the digits typed into the entry, "entry.0" to "entry.9"
+const ENTRY_ACTION_PREFIX = "entry."
+
+// Pseudo key whose binding is the action that receives the number typed,
+// e.g. player.jump on the player screen and explorer.select on the lists
+const ENTRY_KEY = "ENTRY"
+
+type EntryConfig struct {
+	// Time after the last digit when the number is sent
+	Timeout int `toml:"timeout_ms"`
+	// Digits accepted; the number is sent as soon as they are typed
+	MaxDigits int `toml:"max_digits"`
+}
+
+// Notification is shown by the display over the current screen.
+type Notification struct {
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Text      string `json:"text"`
+	Duration  int    `json:"duration_ms,omitempty"`
+}
+
+// Entry buffers the digits of a number typed on the remote, like the track
+// number of a CD player. The number is shown on the display as it is typed
+// and published as the action bound to ENTRY_KEY once the timeout passes,
+// nav.ok is pressed or all the digits are typed. nav.back erases a digit.
+type Entry struct {
+	mu       sync.Mutex
+	bindings Bindings
+	config   EntryConfig
+	// where the number and the digits typed so far are sent
+	publish func(action ActionData)
+	notify  func(notification Notification)
+
+	digits  string
+	context string
+	timer   *time.Timer
+}
+
+func NewEntry(q *queue.Queue, bindings Bindings, config EntryConfig) *Entry {
+	return &Entry{
+		bindings: bindings,
+		config:   config,
+		publish:  func(action ActionData) { publishAction(q, action) },
+		notify:   func(notification Notification) { notify(q, notification) },
+	}
+}
+
+// Handle takes the action of a key pressed on screen, returning false when
+// the entry is not interested and the action must be published as usual.
+func (e *Entry) Handle(screen string, action string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if digit, ok := strings.CutPrefix(action, ENTRY_ACTION_PREFIX); ok {
+		if e.digits == "" {
+			// nothing would receive the number on this screen
+			if _, ok := e.bindings.Resolve(screen, ENTRY_KEY, GESTURE_PRESS); !ok {
+				return true
+			}
+			e.context = screen
+		}
+		e.digits += digit
+		if e.config.MaxDigits > 0 && len(e.digits) >= e.config.MaxDigits {
+			e.commit()
+			return true
+		}
+		e.show()
+		return true
+	}
+
+	if e.digits == "" {
+		return false
+	}
+	switch action {
+	case "nav.ok":
+		e.commit()
+	case "nav.back":
+		e.digits = e.digits[:len(e.digits)-1]
+		if e.digits == "" {
+			e.cancel()
+			return true
+		}
+		e.show()
+	default:
+		// any other key abandons the number
+		e.cancel()
+		return false
+	}
+	return true
+}
+
+// show displays the digits typed so far and restarts the timeout. Must be
+// called with mu locked.
+func (e *Entry) show() {
+	timeout := time.Duration(e.config.Timeout) * time.Millisecond
+	e.notify(Notification{Title: "Go to", Text: e.digits + "_", Duration: e.config.Timeout})
+
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		// a digit typed while the timer fired started a new timeout
+		if e.timer == timer {
+			e.commit()
+		}
+	})
+	e.timer = timer
+}
+
+// commit publishes the number typed. Must be called with mu locked.
+func (e *Entry) commit() {
+	number, err := strconv.Atoi(e.digits)
+	context := e.context
+	e.cancel()
+	if err != nil || number == 0 {
+		return
+	}
+
+	action, ok := e.bindings.Resolve(context, ENTRY_KEY, GESTURE_PRESS)
+	if !ok {
+		return
+	}
+	e.notify(Notification{Title: "Go to", Text: strconv.Itoa(number), Duration: 600})
+	e.publish(ActionData{Action: action, Key: ENTRY_KEY, Context: context, Number: number})
+}
+
+// cancel drops the digits typed. Must be called with mu locked.
+func (e *Entry) cancel() {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	e.digits = ""
+}
+
+func notify(q *queue.Queue, notification Notification) {
+	notification.Timestamp = time.Now().Format("02-01-2006T15:04:05.000")
+	notification.Type = "message"
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Error serializing Notification: %v", err)
+		return
+	}
+
+	if err := q.Publish(NOTIFICATION_TOPIC, string(data)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", NOTIFICATION_TOPIC, err)
+	}
+}
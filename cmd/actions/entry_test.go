@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestEntry(config EntryConfig) (*Entry, chan ActionData) {
+	published := make(chan ActionData, 8)
+	entry := NewEntry(nil, merge(nil), config)
+	entry.publish = func(action ActionData) { published <- action }
+	entry.notify = func(notification Notification) {}
+	return entry, published
+}
+
+// typeKeys passes the actions to entry, failing when one of them is not
+// handled as expected.
+func typeKeys(t *testing.T, entry *Entry, screen string, handled bool, actions ...string) {
+	t.Helper()
+	for _, action := range actions {
+		if got := entry.Handle(screen, action); got != handled {
+			t.Fatalf("Handle(%q, %q) = %v, want %v", screen, action, got, handled)
+		}
+	}
+}
+
+func expectNumber(t *testing.T, published chan ActionData, action string, number int) {
+	t.Helper()
+	select {
+	case got := <-published:
+		if got.Action != action || got.Number != number || got.Key != ENTRY_KEY {
+			t.Errorf("got %s %d from %s, want %s %d", got.Action, got.Number, got.Key, action, number)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("got nothing, want %s %d", action, number)
+	}
+}
+
+func expectNothing(t *testing.T, published chan ActionData, wait time.Duration) {
+	t.Helper()
+	select {
+	case got := <-published:
+		t.Errorf("got %s %d, want nothing", got.Action, got.Number)
+	case <-time.After(wait):
+	}
+}
+
+func TestEntryTimeout(t *testing.T) {
+	entry, published := newTestEntry(EntryConfig{Timeout: 50, MaxDigits: 3})
+
+	typeKeys(t, entry, "player", true, "entry.1", "entry.2")
+	expectNumber(t, published, "player.jump", 12)
+}
+
+func TestEntryMaxDigits(t *testing.T) {
+	entry, published := newTestEntry(EntryConfig{Timeout: 60000, MaxDigits: 3})
+
+	typeKeys(t, entry, "explorer", true, "entry.1", "entry.0", "entry.7")
+	expectNumber(t, published, "explorer.select", 107)
+}
+
+func TestEntryOk(t *testing.T) {
+	entry, published := newTestEntry(EntryConfig{Timeout: 60000, MaxDigits: 3})
+
+	typeKeys(t, entry, "player", true, "entry.5", "nav.ok")
+	expectNumber(t, published, "player.jump", 5)
+}
+
+func TestEntryBackErases(t *testing.T) {
+	entry, published := newTestEntry(EntryConfig{Timeout: 60000, MaxDigits: 3})
+
+	typeKeys(t, entry, "player", true, "entry.1", "entry.2", "nav.back", "entry.3", "nav.ok")
+	expectNumber(t, published, "player.jump", 13)
+
+	// erasing the last digit leaves the entry, the next back is a usual one
+	typeKeys(t, entry, "player", true, "entry.4", "nav.back")
+	typeKeys(t, entry, "player", false, "nav.back")
+	expectNothing(t, published, 50*time.Millisecond)
+}
+
+func TestEntryOtherKeyCancels(t *testing.T) {
+	entry, published := newTestEntry(EntryConfig{Timeout: 50, MaxDigits: 3})
+
+	typeKeys(t, entry, "player", true, "entry.4")
+	typeKeys(t, entry, "player", false, "player.next")
+	expectNothing(t, published, 150*time.Millisecond)
+}
+
+func TestEntryZeroDropped(t *testing.T) {
+	entry, published := newTestEntry(EntryConfig{Timeout: 60000, MaxDigits: 3})
+
+	typeKeys(t, entry, "player", true, "entry.0", "nav.ok")
+	typeKeys(t, entry, "player", true, "entry.0", "entry.0", "entry.0")
+	expectNothing(t, published, 50*time.Millisecond)
+}
+
+func TestEntryUnboundScreen(t *testing.T) {
+	entry, published := newTestEntry(EntryConfig{Timeout: 60000, MaxDigits: 1})
+
+	// digits are swallowed where nothing receives the number
+	typeKeys(t, entry, "search", true, "entry.3")
+	typeKeys(t, entry, "search", false, "nav.ok")
+	expectNothing(t, published, 50*time.Millisecond)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"media-player/pkg/queue"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const PLAYER_COMMAND_TOPIC = "player-command"
+
+// Actions "macro.<name>" run the macro name of the configuration
+const MACRO_ACTION_PREFIX = "macro."
+
+// Returned by the steps of a macro interrupted by the service stopping
+var errStopped = errors.New("stopped")
+
+// MacroStep is one step of a macro: an action published as if its key was
+// pressed, another macro run in its place, a saved playlist loaded into the
+// player, or a pause.
+type MacroStep struct {
+	Action string `toml:"action"`
+	// Number and value sent with the action, e.g. the root opened by
+	// explorer.root
+	Number int    `toml:"number"`
+	Value  string `toml:"value"`
+	// Playlist name, loaded from <playlists>/<name>.m3u
+	Playlist string `toml:"playlist"`
+	Shuffle  bool   `toml:"shuffle"`
+	// Time waited after the step
+	Delay int `toml:"delay_ms"`
+}
+
+// PlayerCommand loads the tracks of a playlist into the player.
+type PlayerCommand struct {
+	Timestamp string   `json:"timestamp"`
+	Action    string   `json:"action"`
+	Tracks    []string `json:"tracks,omitempty"`
+	Index     int      `json:"index,omitempty"`
+}
+
+// Runner runs macros one at a time, in the order their keys were pressed,
+// so a long macro never interleaves with the next one.
+type Runner struct {
+	q         *queue.Queue
+	macros    map[string][]MacroStep
+	playlists string
+	pending   chan string
+}
+
+func NewRunner(q *queue.Queue, macros map[string][]MacroStep, playlists string) *Runner {
+	return &Runner{q: q, macros: macros, playlists: playlists, pending: make(chan string, 8)}
+}
+
+// Handle queues the macro of action, returning false for other actions.
+func (r *Runner) Handle(action string) bool {
+	name, ok := strings.CutPrefix(action, MACRO_ACTION_PREFIX)
+	if !ok {
+		return false
+	}
+	if _, ok := r.macros[name]; !ok {
+		log.Printf("Unknown macro %s", name)
+		return true
+	}
+
+	select {
+	case r.pending <- name:
+	default:
+		log.Printf("Dropping macro %s, too many macros queued", name)
+	}
+	return true
+}
+
+// Run executes the queued macros until done is closed.
+func (r *Runner) Run(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case name := <-r.pending:
+			log.Printf("Running macro %s", name)
+			err := r.runMacro(name, nil, done)
+			if errors.Is(err, errStopped) {
+				return
+			}
+			if err != nil {
+				log.Printf("Error on %v", err)
+				notify(r.q, Notification{Title: "Macro " + name, Text: err.Error()})
+			}
+		}
+	}
+}
+
+// runMacro runs the steps of the macro name, stopping at the first one that
+// fails. calling holds the macros running it, so a macro never calls itself.
+func (r *Runner) runMacro(name string, calling []string, done <-chan struct{}) error {
+	for _, caller := range calling {
+		if caller == name {
+			return fmt.Errorf("macro %s calls itself", name)
+		}
+	}
+	steps, ok := r.macros[name]
+	if !ok {
+		return fmt.Errorf("unknown macro %s", name)
+	}
+
+	calling = append(calling, name)
+	for i, step := range steps {
+		if err := r.runStep(name, step, calling, done); err != nil {
+			if errors.Is(err, errStopped) {
+				return err
+			}
+			return fmt.Errorf("step %d of macro %s: %w", i+1, name, err)
+		}
+		if step.Delay > 0 {
+			select {
+			case <-done:
+				return errStopped
+			case <-time.After(time.Duration(step.Delay) * time.Millisecond):
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Runner) runStep(name string, step MacroStep, calling []string, done <-chan struct{}) error {
+	switch {
+	case step.Playlist != "":
+		return r.loadPlaylist(step.Playlist, step.Shuffle)
+	case strings.HasPrefix(step.Action, MACRO_ACTION_PREFIX):
+		return r.runMacro(strings.TrimPrefix(step.Action, MACRO_ACTION_PREFIX), calling, done)
+	case step.Action != "":
+		publishAction(r.q, ActionData{
+			Action:  step.Action,
+			Key:     MACRO_ACTION_PREFIX + name,
+			Context: CONTEXT_GLOBAL,
+			Number:  step.Number,
+			Value:   step.Value,
+		})
+	}
+	return nil
+}
+
+func (r *Runner) loadPlaylist(name string, shuffle bool) error {
+	path := filepath.Join(r.playlists, name+".m3u")
+	tracks, err := readPlaylist(path)
+	if err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		return fmt.Errorf("playlist %s is empty", name)
+	}
+	if shuffle {
+		rand.Shuffle(len(tracks), func(i, j int) {
+			tracks[i], tracks[j] = tracks[j], tracks[i]
+		})
+	}
+
+	command := PlayerCommand{
+		Timestamp: time.Now().Format("02-01-2006T15:04:05.000"),
+		Action:    "load",
+		Tracks:    tracks,
+	}
+	data, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	return r.q.Publish(PLAYER_COMMAND_TOPIC, string(data))
+}
+
+// readPlaylist returns the tracks of an M3U playlist. Relative paths are
+// taken from the folder of the playlist.
+func readPlaylist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tracks []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(path), line)
+		}
+		tracks = append(tracks, line)
+	}
+	return tracks, scanner.Err()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"media-player/pkg/config"
+	"media-player/pkg/queue"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const REMOTE_CONTROL_TOPIC = "remote-control"
+const SCREEN_TOPIC = "screen"
+const BACKLIGHT_TOPIC = "backlight"
+const ACTIONS_TOPIC = "actions"
+
+type CommandData struct {
+	Timestamp string `json:"timestamp"`
+	Key       string `json:"key"`
+	Gesture   string `json:"gesture"`
+}
+
+type ScreenState struct {
+	Timestamp string `json:"timestamp"`
+	Screen    string `json:"screen"`
+}
+
+type BacklightState struct {
+	Timestamp string `json:"timestamp"`
+	On        bool   `json:"on"`
+}
+
+// ActionData is a key translated to what it means on the screen shown when
+// it was pressed.
+type ActionData struct {
+	Timestamp string `json:"timestamp"`
+	Action    string `json:"action"`
+	Key       string `json:"key"`
+	Context   string `json:"context"`
+	// Number typed on the remote, for the ENTRY actions
+	Number int `json:"number,omitempty"`
+	// Argument set by a macro, e.g. the root opened by explorer.root
+	Value string `json:"value,omitempty"`
+}
+
+type Config struct {
+	// Screen name ("explorer", "player", ...) or "global", then key name
+	// with an optional gesture, e.g. "KEY_OK:long"
+	Bindings Bindings    `toml:"bindings"`
+	Entry    EntryConfig `toml:"entry"`
+	// Sequences of steps run by the actions "macro.<name>"
+	Macros map[string][]MacroStep `toml:"macros"`
+	// Folder of the playlists loaded by macros
+	Playlists string `toml:"playlists"`
+}
+
+func main() {
+	log.SetFlags(0)
+	configPath := flag.String("config", config.Dir+"/actions.toml", "configuration file")
+	flag.Parse()
+
+	cfg := Config{
+		Entry:     EntryConfig{Timeout: 2000, MaxDigits: 3},
+		Playlists: "/var/lib/media-player/playlists",
+	}
+	if err := config.Load(*configPath, &cfg); err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGABRT)
+	defer stop()
+
+	run(ctx, merge(cfg.Bindings), cfg)
+}
+
+func run(ctx context.Context, bindings Bindings, cfg Config) {
+	q := queue.NewQueue()
+	entry := NewEntry(q, bindings, cfg.Entry)
+	runner := NewRunner(q, cfg.Macros, cfg.Playlists)
+	go runner.Run(ctx.Done())
+
+	if err := q.Subscribe(ctx, handleMessage(q, bindings, entry, runner),
+		REMOTE_CONTROL_TOPIC, SCREEN_TOPIC, BACKLIGHT_TOPIC); err != nil {
+		log.Printf("Error subscribing to topic: %v", err)
+	}
+	log.Println("Stopping service...")
+}
+
+func handleMessage(q *queue.Queue, bindings Bindings, entry *Entry, runner *Runner) func(channel, message string) {
+	screen := CONTEXT_GLOBAL
+	backlightOn := true
+
+	return func(channel, message string) {
+		switch channel {
+		case SCREEN_TOPIC:
+			var state ScreenState
+			if err := json.Unmarshal([]byte(message), &state); err != nil {
+				log.Printf("Error parsing JSON for ScreenState: %v", err)
+				return
+			}
+			screen = state.Screen
+
+		case BACKLIGHT_TOPIC:
+			var state BacklightState
+			if err := json.Unmarshal([]byte(message), &state); err != nil {
+				log.Printf("Error parsing JSON for BacklightState: %v", err)
+				return
+			}
+			backlightOn = state.On
+
+		case REMOTE_CONTROL_TOPIC:
+			// The key that wakes the display up must not trigger an action
+			if !backlightOn {
+				backlightOn = true
+				return
+			}
+
+			var command CommandData
+			if err := json.Unmarshal([]byte(message), &command); err != nil {
+				log.Printf("Error parsing JSON for CommandData: %v", err)
+				return
+			}
+
+			action, ok := bindings.Resolve(screen, command.Key, command.Gesture)
+			if !ok || entry.Handle(screen, action) || runner.Handle(action) {
+				return
+			}
+			publishAction(q, ActionData{Action: action, Key: command.Key, Context: screen})
+		}
+	}
+}
+
+func publishAction(q *queue.Queue, action ActionData) {
+	action.Timestamp = time.Now().Format("02-01-2006T15:04:05.000")
+
+	data, err := json.Marshal(action)
+	if err != nil {
+		log.Printf("Error serializing ActionData: %v", err)
+		return
+	}
+
+	if err := q.Publish(ACTIONS_TOPIC, string(data)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", ACTIONS_TOPIC, err)
+	}
+}
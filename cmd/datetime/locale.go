@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Names of the weekdays, from Sunday, and of the months, from January.
+type Locale struct {
+	Days        [7]string
+	ShortDays   [7]string
+	Months      [12]string
+	ShortMonths [12]string
+}
+
+// Languages with localized names. English uses the names of the time
+// package. Names are written without accents, since the display measures
+// text in bytes and its character ROM has no accented letters.
+var locales = map[string]*Locale{
+	"pt": {
+		Days:        [7]string{"Domingo", "Segunda", "Terca", "Quarta", "Quinta", "Sexta", "Sabado"},
+		ShortDays:   [7]string{"Dom", "Seg", "Ter", "Qua", "Qui", "Sex", "Sab"},
+		Months:      [12]string{"Janeiro", "Fevereiro", "Marco", "Abril", "Maio", "Junho", "Julho", "Agosto", "Setembro", "Outubro", "Novembro", "Dezembro"},
+		ShortMonths: [12]string{"Jan", "Fev", "Mar", "Abr", "Mai", "Jun", "Jul", "Ago", "Set", "Out", "Nov", "Dez"},
+	},
+	"es": {
+		Days:        [7]string{"Domingo", "Lunes", "Martes", "Miercoles", "Jueves", "Viernes", "Sabado"},
+		ShortDays:   [7]string{"Dom", "Lun", "Mar", "Mie", "Jue", "Vie", "Sab"},
+		Months:      [12]string{"Enero", "Febrero", "Marzo", "Abril", "Mayo", "Junio", "Julio", "Agosto", "Septiembre", "Octubre", "Noviembre", "Diciembre"},
+		ShortMonths: [12]string{"Ene", "Feb", "Mar", "Abr", "May", "Jun", "Jul", "Ago", "Sep", "Oct", "Nov", "Dic"},
+	},
+}
+
+// Format formats t like time.Format, with the weekday and month names
+// (Monday, Mon, January, Jan) of the locale. A nil locale is English.
+func (l *Locale) Format(t time.Time, layout string) string {
+	if l == nil {
+		return t.Format(layout)
+	}
+
+	// The names are written apart from the rest of the layout, since a
+	// localized name could be read as a layout element, e.g. "Janeiro".
+	var b strings.Builder
+	rest := layout
+	for rest != "" {
+		i, name, length := l.nextName(t, rest)
+		if i < 0 {
+			b.WriteString(t.Format(rest))
+			break
+		}
+		b.WriteString(t.Format(rest[:i]))
+		b.WriteString(name)
+		rest = rest[i+length:]
+	}
+	return b.String()
+}
+
+// nextName finds the first weekday or month element of layout, returning
+// its position, the localized name and the length of the element, or -1
+// when there is none. Longer elements are matched first, as time.Format
+// does.
+func (l *Locale) nextName(t time.Time, layout string) (int, string, int) {
+	for i := 0; i < len(layout); i++ {
+		rest := layout[i:]
+		switch {
+		case strings.HasPrefix(rest, "January"):
+			return i, l.Months[t.Month()-1], len("January")
+		case strings.HasPrefix(rest, "Jan"):
+			return i, l.ShortMonths[t.Month()-1], len("Jan")
+		case strings.HasPrefix(rest, "Monday"):
+			return i, l.Days[t.Weekday()], len("Monday")
+		case strings.HasPrefix(rest, "Mon"):
+			return i, l.ShortDays[t.Weekday()], len("Mon")
+		}
+	}
+	return -1, "", 0
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleFormat(t *testing.T) {
+	// a Saturday in March
+	at := time.Date(2024, time.March, 2, 9, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		language string
+		layout   string
+		want     string
+	}{
+		{"en", "Mon 02 Jan", "Sat 02 Mar"},
+		{"pt", "Mon 02 Jan", "Sab 02 Mar"},
+		{"es", "Mon 02 Jan", "Sab 02 Mar"},
+		{"en", "Monday, January 2", "Saturday, March 2"},
+		{"pt", "Monday, January 2", "Sabado, Marco 2"},
+		{"es", "Monday, January 2", "Sabado, Marzo 2"},
+		{"pt", "15:04 02/01/2006", "09:05 02/03/2024"},
+		{"es", "15:04 02/01/2006", "09:05 02/03/2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language+" "+tt.layout, func(t *testing.T) {
+			if got := locales[tt.language].Format(at, tt.layout); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
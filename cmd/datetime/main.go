@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"log"
+	"media-player/pkg/config"
 	"media-player/pkg/queue"
 	"os"
 	"os/signal"
@@ -15,26 +17,67 @@ const TOPIC = "datetime"
 
 type DateTime struct {
 	Timestamp string `json:"timestamp"`
-	Date      string `json:"date"`
-	Time      string `json:"time"`
+	// Date and time ready to be shown, in the configured layouts
+	Date string `json:"date"`
+	Time string `json:"time"`
+	// Time as "15:04:05" whatever the layout, for the big clock
+	Clock string `json:"clock"`
+	// Abbreviation of the zone, e.g. "-03" or "CEST" during DST
+	Zone string `json:"zone"`
+	// The same instant in UTC, as RFC 3339
+	UTC string `json:"utc"`
+}
+
+type Config struct {
+	// IANA zone, e.g. "America/Sao_Paulo". Empty uses the system zone.
+	Timezone string `toml:"timezone"`
+	// Layouts of the time package, e.g. "Mon 02 Jan" or "3:04 PM"
+	DateLayout string `toml:"date_layout"`
+	TimeLayout string `toml:"time_layout"`
+	// Language of the weekday and month names: "en", "pt" or "es"
+	Language string `toml:"language"`
 }
 
 func main() {
 	log.SetFlags(0)
+	configPath := flag.String("config", config.Dir+"/datetime.toml", "configuration file")
+	flag.Parse()
+
+	cfg := Config{
+		DateLayout: "02-01-2006",
+		TimeLayout: "15:04:05",
+		Language:   "en",
+	}
+	if err := config.Load(*configPath, &cfg); err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	loc := time.Local
+	if cfg.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			log.Fatalf("Error loading timezone: %v", err)
+		}
+	}
+
+	locale, ok := locales[cfg.Language]
+	if !ok && cfg.Language != "en" {
+		log.Fatalf("Unknown language %s", cfg.Language)
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(),
 		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGABRT)
 	defer stop()
 
-	run(ctx)
+	run(ctx, cfg, loc, locale)
 }
 
-func run(ctx context.Context) {
+func run(ctx context.Context, cfg Config, loc *time.Location, locale *Locale) {
 	q := queue.NewQueue()
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
-	loc := time.FixedZone("GMT-3", -3*60*60)
+	log.Printf("Publishing time of zone %s", loc)
 	for {
 		select {
 		case <-ctx.Done():
@@ -42,10 +85,15 @@ func run(ctx context.Context) {
 			return
 
 		case t := <-ticker.C:
+			local := t.In(loc)
+			zone, _ := local.Zone()
 			datetime := DateTime{
-				Timestamp: t.In(loc).Format("02-01-2006T15:04:05.000"),
-				Date:      t.In(loc).Format("02-01-2006"),
-				Time:      t.In(loc).Format("15:04:05"),
+				Timestamp: local.Format("02-01-2006T15:04:05.000"),
+				Date:      locale.Format(local, cfg.DateLayout),
+				Time:      locale.Format(local, cfg.TimeLayout),
+				Clock:     local.Format("15:04:05"),
+				Zone:      zone,
+				UTC:       t.UTC().Format(time.RFC3339),
 			}
 
 			data, err := json.Marshal(datetime)
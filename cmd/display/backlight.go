@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"media-player/pkg/display"
+	"media-player/pkg/queue"
+	"sync"
+	"time"
+)
+
+const BACKLIGHT_TOPIC = "backlight"
+
+type BacklightState struct {
+	Timestamp string `json:"timestamp"`
+	On        bool   `json:"on"`
+}
+
+type BacklightConfig struct {
+	// Minutes without input or player changes before turning off. Zero keeps it on.
+	IdleTimeout int `toml:"idle_timeout_minutes"`
+	// Night mode window in "15:04" format. Empty values disable it.
+	NightStart string `toml:"night_start"`
+	NightEnd   string `toml:"night_end"`
+	// Seconds the backlight stays on after a wake during night mode.
+	NightWake int `toml:"night_wake_seconds"`
+}
+
+type Backlight struct {
+	mu        sync.Mutex
+	screen    *display.Display
+	q         *queue.Queue
+	config    BacklightConfig
+	on        bool
+	wasNight  bool
+	idleTimer *time.Timer
+}
+
+func NewBacklight(screen *display.Display, q *queue.Queue, config BacklightConfig) *Backlight {
+	return &Backlight{screen: screen, q: q, config: config}
+}
+
+// Wake turns the backlight on, if needed, and restarts the idle countdown.
+func (b *Backlight) Wake() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.on {
+		b.set(true)
+	}
+
+	if b.idleTimer != nil {
+		b.idleTimer.Stop()
+	}
+
+	timeout := b.idleTimeout()
+	if timeout == 0 {
+		return
+	}
+	b.idleTimer = time.AfterFunc(timeout, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.set(false)
+	})
+}
+
+// Off turns the backlight off and stops the idle countdown.
+func (b *Backlight) Off() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.idleTimer != nil {
+		b.idleTimer.Stop()
+	}
+	b.set(false)
+}
+
+// Run turns the backlight off when the night mode window starts.
+func (b *Backlight) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			night := b.isNight(time.Now())
+
+			b.mu.Lock()
+			enteringNight := night && !b.wasNight
+			b.wasNight = night
+			b.mu.Unlock()
+
+			if enteringNight {
+				b.Off()
+			}
+		}
+	}
+}
+
+func (b *Backlight) idleTimeout() time.Duration {
+	if b.isNight(time.Now()) {
+		seconds := b.config.NightWake
+		if seconds <= 0 {
+			seconds = 30
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(b.config.IdleTimeout) * time.Minute
+}
+
+func (b *Backlight) isNight(now time.Time) bool {
+	start, err := time.Parse("15:04", b.config.NightStart)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", b.config.NightEnd)
+	if err != nil {
+		return false
+	}
+
+	current := now.Hour()*60 + now.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+
+	if from <= to {
+		return current >= from && current < to
+	}
+	// window crosses midnight
+	return current >= from || current < to
+}
+
+func (b *Backlight) set(on bool) {
+	var err error
+	if on {
+		err = b.screen.TurnBacklightOn()
+	} else {
+		err = b.screen.TurnBacklightOff()
+	}
+	if err != nil {
+		log.Printf("Error switching backlight: %v", err)
+		return
+	}
+	if b.on == on {
+		return
+	}
+	b.on = on
+
+	state := BacklightState{
+		Timestamp: time.Now().Format("02-01-2006T15:04:05.000"),
+		On:        on,
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("Error serializing BacklightState: %v", err)
+		return
+	}
+	if err := b.q.Publish(BACKLIGHT_TOPIC, string(data)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", BACKLIGHT_TOPIC, err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"media-player/pkg/display"
+	"strings"
+)
+
+const (
+	CLOCK_TEXT = "text"
+	CLOCK_BIG  = "big"
+)
+
+type ClockConfig struct {
+	// "text" shows date and time, "big" shows HH:MM with two-row digits
+	Style string `toml:"style"`
+}
+
+// Segments stored in CGRAM to build the two-row digits, as used by the
+// classic "big font" for HD44780 displays.
+var bigFontGlyphs = [8][8]byte{
+	{0x07, 0x0F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}, // 0: left top
+	{0x1F, 0x1F, 0x1F, 0x00, 0x00, 0x00, 0x00, 0x00}, // 1: upper bar
+	{0x1C, 0x1E, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}, // 2: right top
+	{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x0F, 0x07}, // 3: left bottom
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x1F, 0x1F, 0x1F}, // 4: lower bar
+	{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1E, 0x1C}, // 5: right bottom
+	{0x1F, 0x1F, 0x1F, 0x00, 0x00, 0x00, 0x1F, 0x1F}, // 6: upper middle bars
+	{0x1F, 0x00, 0x00, 0x00, 0x00, 0x1F, 0x1F, 0x1F}, // 7: lower middle bars
+}
+
+// Top and bottom rows of each digit, three columns wide.
+var bigDigits = [10][2]string{
+	{"\x00\x01\x02", "\x03\x04\x05"},
+	{"\x01\x02 ", "\x04\xff\x04"},
+	{"\x06\x06\x02", "\x03\x07\x07"},
+	{"\x06\x06\x02", "\x07\x07\x05"},
+	{"\x03\x04\xff", "  \xff"},
+	{"\xff\x06\x06", "\x07\x07\x05"},
+	{"\x00\x06\x06", "\x03\x07\x05"},
+	{"\x01\x01\x02", "  \xff"},
+	{"\x00\x06\x02", "\x03\x07\x05"},
+	{"\x00\x06\x02", "  \xff"},
+}
+
+// Middle dot from the LCD character ROM.
+const bigColon = "\xa5"
+
+func loadBigFont(screen *display.Display) error {
+	for i, glyph := range bigFontGlyphs {
+		if err := screen.CreateChar(i, glyph); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getBigClockLines renders the "15:04:05" time as HH:MM in two rows. The
+// colon blinks on odd seconds.
+func getBigClockLines(clock string) (string, string) {
+	if len(clock) < 5 {
+		return clock, ""
+	}
+
+	colon := bigColon
+	if len(clock) >= 8 && (clock[7]-'0')%2 == 1 {
+		colon = " "
+	}
+
+	var top, bottom strings.Builder
+	for i, c := range clock[:5] {
+		switch {
+		case c == ':':
+			top.WriteString(colon)
+			bottom.WriteString(colon)
+		case c >= '0' && c <= '9':
+			digit := bigDigits[c-'0']
+			top.WriteString(digit[0])
+			bottom.WriteString(digit[1])
+			if i == 0 || i == 3 {
+				top.WriteString(" ")
+				bottom.WriteString(" ")
+			}
+		}
+	}
+	return top.String(), bottom.String()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+)
+
+const displayWidth = 16
+
+const (
+	SCREEN_DATETIME = "datetime"
+	SCREEN_EXPLORER = "explorer"
+	SCREEN_PLAYER   = "player"
+	SCREEN_SEARCH   = "search"
+)
+
+// Built-in layouts, used for every screen not defined in the layouts file.
+var defaultLayouts = map[string][2]string{
+	SCREEN_DATETIME: {
+		`{{center .Date}}`,
+		`{{center .Time}}`,
+	},
+	SCREEN_EXPLORER: {
+		`{{fit 15 (index .Rows 0).Label}}{{if .ScrollUp}}|{{end}}`,
+		`{{fit 15 (index .Rows 1).Label}}{{if .ScrollDown}}|{{end}}`,
+	},
+	SCREEN_SEARCH: {
+		`{{fit 16 (print "Find:" .Search.Query (or .Search.Char "_"))}}`,
+		`{{if .Items}}{{fit 16 (index .Rows 0).Name}}{{else}}No results{{end}}`,
+	},
+	SCREEN_PLAYER: {
+		`{{.Media.Data.Artist | scroll}}`,
+		`{{if eq .Media.State "paused"}}{{glyph "pause"}}{{else}}{{glyph "play"}}{{end}} {{.Media.Time.Current}}/{{.Media.Time.Total}}`,
+	},
+}
+
+var bigClockLayout = [2]string{
+	`{{center (bigclock .Clock 1)}}`,
+	`{{center (bigclock .Clock 2)}}`,
+}
+
+// Characters from the HD44780 A00 ROM that can be used in layouts.
+var glyphs = map[string]byte{
+	"block": 0xff,
+	"dot":   0xa5,
+	"right": 0x7e,
+	"left":  0x7f,
+	"play":  0x7e,
+	"pause": 0xdb,
+}
+
+type LayoutFile struct {
+	Screens map[string]LayoutConfig `toml:"screens"`
+}
+
+type LayoutConfig struct {
+	Line1 string `toml:"line1"`
+	Line2 string `toml:"line2"`
+}
+
+type Layout struct {
+	lines [2]*template.Template
+	// render count, used to move scrolling text
+	tick int
+}
+
+type Layouts struct {
+	mu         sync.Mutex
+	path       string
+	clockStyle string
+	screens    map[string]*Layout
+}
+
+func NewLayouts(path string, clockStyle string) (*Layouts, error) {
+	layouts := &Layouts{path: path, clockStyle: clockStyle}
+	if err := layouts.Reload(); err != nil {
+		return nil, err
+	}
+	return layouts, nil
+}
+
+// Reload parses the layouts file again. On error the current layouts are
+// kept.
+func (l *Layouts) Reload() error {
+	file := LayoutFile{Screens: map[string]LayoutConfig{}}
+	if l.path != "" {
+		if _, err := toml.DecodeFile(l.path, &file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	sources := map[string][2]string{}
+	for name, lines := range defaultLayouts {
+		sources[name] = lines
+	}
+	for name, screen := range file.Screens {
+		sources[name] = [2]string{screen.Line1, screen.Line2}
+	}
+	// the clock style is chosen explicitly, so it wins over the file
+	if l.clockStyle == CLOCK_BIG {
+		sources[SCREEN_DATETIME] = bigClockLayout
+	}
+
+	screens := map[string]*Layout{}
+	for name, lines := range sources {
+		layout, err := parseLayout(name, lines)
+		if err != nil {
+			return err
+		}
+		screens[name] = layout
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.screens = screens
+	return nil
+}
+
+// Render executes the screen layout with data. Lines are padded to the
+// display width, so they fully overwrite the previous content.
+func (l *Layouts) Render(name string, data any) (string, string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	layout, ok := l.screens[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown screen %s", name)
+	}
+	layout.tick++
+
+	var lines [2]string
+	for i, tmpl := range layout.lines {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", "", err
+		}
+		lines[i] = fit(displayWidth, b.String())
+	}
+	return lines[0], lines[1], nil
+}
+
+func parseLayout(name string, lines [2]string) (*Layout, error) {
+	layout := &Layout{}
+	funcs := template.FuncMap{
+		"left":   func(s string) string { return fit(displayWidth, s) },
+		"center": center,
+		"right":  right,
+		"fit":    fit,
+		"scroll": func(s string) string { return scroll(s, layout.tick) },
+		"glyph":  glyph,
+		"char":   func(n int) string { return string([]byte{byte(n & 0x07)}) },
+		"bigclock": func(clock string, row int) string {
+			top, bottom := getBigClockLines(clock)
+			if row == 2 {
+				return bottom
+			}
+			return top
+		},
+	}
+
+	for i, line := range lines {
+		tmpl, err := template.New(fmt.Sprintf("%s.line%d", name, i+1)).Funcs(funcs).Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		layout.lines[i] = tmpl
+	}
+	return layout, nil
+}
+
+func fit(width int, s string) string {
+	if len(s) > width {
+		return s[:width]
+	}
+	return s + spaces(width-len(s))
+}
+
+func center(s string) string {
+	if len(s) >= displayWidth {
+		return s[:displayWidth]
+	}
+	return fit(displayWidth, spaces((displayWidth-len(s))/2)+s)
+}
+
+func right(s string) string {
+	if len(s) >= displayWidth {
+		return s[:displayWidth]
+	}
+	return spaces(displayWidth-len(s)) + s
+}
+
+// scroll moves text longer than the display one character per render.
+func scroll(s string, tick int) string {
+	if len(s) <= displayWidth {
+		return s
+	}
+	text := s + spaces(3)
+	offset := tick % len(text)
+	return (text + text)[offset : offset+displayWidth]
+}
+
+func glyph(name string) string {
+	c, ok := glyphs[name]
+	if !ok {
+		return "?"
+	}
+	return string([]byte{c})
+}
+
+func spaces(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat(" ", n)
+}
@@ -3,13 +3,14 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"media-player/pkg/config"
 	"media-player/pkg/display"
 	"media-player/pkg/queue"
 	"os"
 	"os/signal"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -24,6 +25,9 @@ type DateTime struct {
 	Timestamp string `json:"timestamp"`
 	Date      string `json:"date"`
 	Time      string `json:"time"`
+	Clock     string `json:"clock"`
+	Zone      string `json:"zone"`
+	UTC       string `json:"utc"`
 }
 
 type Item struct {
@@ -33,21 +37,98 @@ type Item struct {
 }
 
 type Explorer struct {
-	Timestamp     string `json:"timestamp"`
-	CurrentDir    string `json:"current_dir"`
-	HasNext       bool   `json:"has_next`
-	HasPrevious   bool   `json:"has_previous`
-	SelectedIndex int    `json:"selected_index"`
-	Items         []Item `json:"items"`
+	Timestamp     string  `json:"timestamp"`
+	Root          string  `json:"root"`
+	CurrentDir    string  `json:"current_dir"`
+	SortMode      string  `json:"sort_mode"`
+	Letter        string  `json:"letter,omitempty"`
+	HasNext       bool    `json:"has_next"`
+	HasPrevious   bool    `json:"has_previous"`
+	SelectedIndex int     `json:"selected_index"`
+	Items         []Item  `json:"items"`
+	Search        *Search `json:"search,omitempty"`
+}
+
+// Search is the character picker of the explorer, present while a query
+// is being typed.
+type Search struct {
+	Query string `json:"query"`
+	Char  string `json:"char"`
+}
+
+type CommandData struct {
+	Timestamp string `json:"timestamp"`
+	Key       string `json:"key"`
+}
+
+type Time struct {
+	Current string `json:"current"`
+	Total   string `json:"total"`
+}
+
+type Data struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+}
+
+type Media struct {
+	Type  string `json:"type"`
+	State string `json:"state"`
+	Time  Time   `json:"time"`
+	Data  Data   `json:"data"`
+}
+
+type PlayerData struct {
+	Timestamp string `json:"timestamp"`
+	Media     Media  `json:"media"`
+}
+
+type Config struct {
+	Backlight BacklightConfig `toml:"backlight"`
+	Clock     ClockConfig     `toml:"clock"`
+	// Layouts file for the screens, reloaded on SIGHUP
+	Layouts string `toml:"layouts"`
 }
 
 const DATETIME_TOPIC = "datetime"
 const FILE_EXPLORER_TOPIC = "file-explorer"
+const NOTIFICATION_TOPIC = "notification"
+const REMOTE_CONTROL_TOPIC = "remote-control"
+const PLAYER_TOPIC = "player"
 
 func main() {
 	log.SetFlags(0)
+	configPath := flag.String("config", config.Dir+"/display.toml", "configuration file")
+	flag.Parse()
+
+	cfg := Config{
+		Backlight: BacklightConfig{IdleTimeout: 10, NightWake: 30},
+		Clock:     ClockConfig{Style: CLOCK_TEXT},
+		Layouts:   config.Dir + "/screens.toml",
+	}
+	if err := config.Load(*configPath, &cfg); err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	layouts, err := NewLayouts(cfg.Layouts, cfg.Clock.Style)
+	if err != nil {
+		log.Fatalf("Error loading layouts: %v", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			if err := layouts.Reload(); err != nil {
+				log.Printf("Error reloading layouts: %v", err)
+				continue
+			}
+			log.Println("Layouts reloaded")
+		}
+	}()
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGABRT)
 
@@ -73,31 +154,68 @@ func main() {
 		log.Printf("Error clearing display: %v", err)
 	}
 
-	if err := screen.TurnBacklightOn(); err != nil {
-		log.Printf("Error turning on backlight: %v", err)
+	if cfg.Clock.Style == CLOCK_BIG {
+		if err := loadBigFont(screen); err != nil {
+			log.Printf("Error loading big clock font: %v", err)
+		}
 	}
 
 	q := queue.NewQueue()
-	if err := q.Subscribe(ctx, handleMessage(screen), DATETIME_TOPIC, FILE_EXPLORER_TOPIC); err != nil {
+	activeScreen = NewActiveScreen(q)
+	backlight := NewBacklight(screen, q, cfg.Backlight)
+	backlight.Wake()
+	go backlight.Run(ctx.Done())
+	go watchRedis(ctx, q, screen)
+
+	if err := q.Subscribe(ctx, handleMessage(screen, backlight, layouts),
+		DATETIME_TOPIC, FILE_EXPLORER_TOPIC, NOTIFICATION_TOPIC, REMOTE_CONTROL_TOPIC, PLAYER_TOPIC); err != nil {
 		log.Fatalf("Error subscribing to topic: %v", err)
 	}
 }
 
-func handleMessage(screen *display.Display) func(channel, message string) {
+func handleMessage(screen *display.Display, backlight *Backlight, layouts *Layouts) func(channel, message string) {
 	var allowDateTime = true
+	var playerState string
 
 	return func(channel, message string) {
 		switch channel {
+		case REMOTE_CONTROL_TOPIC:
+			backlight.Wake()
+
+		case PLAYER_TOPIC:
+			var playerData PlayerData
+			if err := json.Unmarshal([]byte(message), &playerData); err != nil {
+				log.Printf("Error parsing JSON for player: %v", err)
+				return
+			}
+			if playerData.Media.State != playerState {
+				playerState = playerData.Media.State
+				backlight.Wake()
+			}
+			if !allowDateTime || !isPlaying(playerState) {
+				return
+			}
+			showScreen(screen, layouts, SCREEN_PLAYER, &playerData, false)
+
 		case FILE_EXPLORER_TOPIC:
 			var explorer Explorer
 			if err := json.Unmarshal([]byte(message), &explorer); err != nil {
 				log.Printf("Error parsing JSON for explorer: %v", err)
 				return
 			}
-			handleExplorerUpdate(&explorer, screen, &allowDateTime)
+			handleExplorerUpdate(&explorer, screen, layouts, &allowDateTime)
+
+		case NOTIFICATION_TOPIC:
+			var notification Notification
+			if err := json.Unmarshal([]byte(message), &notification); err != nil {
+				log.Printf("Error parsing JSON for notification: %v", err)
+				return
+			}
+			backlight.Wake()
+			showOverlay(screen, &notification)
 
 		case DATETIME_TOPIC:
-			if !allowDateTime {
+			if !allowDateTime || isPlaying(playerState) {
 				return
 			}
 
@@ -106,12 +224,7 @@ func handleMessage(screen *display.Display) func(channel, message string) {
 				log.Printf("Error parsing JSON for datetime: %v", err)
 				return
 			}
-			if err := screen.ShowText(datetime.Date, 1, display.CENTER); err != nil {
-				log.Printf("Error showing date: %v", err)
-			}
-			if err := screen.ShowText(datetime.Time, 2, display.CENTER); err != nil {
-				log.Printf("Error showing time: %v", err)
-			}
+			showScreen(screen, layouts, SCREEN_DATETIME, &datetime, false)
 
 		default:
 			log.Printf("Received message on unknown topic: %s", channel)
@@ -119,17 +232,16 @@ func handleMessage(screen *display.Display) func(channel, message string) {
 	}
 }
 
-func handleExplorerUpdate(explorer *Explorer, screen *display.Display, allowDateTime *bool) {
-	line1, line2 := getExplorerLines(explorer)
-
-	if err := screen.Clear(); err != nil {
-		log.Printf("Error cleaning display: %v", err)
+func handleExplorerUpdate(explorer *Explorer, screen *display.Display, layouts *Layouts, allowDateTime *bool) {
+	if explorer.Search != nil {
+		showScreen(screen, layouts, SCREEN_SEARCH, NewExplorerView(explorer), true)
+	} else {
+		showScreen(screen, layouts, SCREEN_EXPLORER, NewExplorerView(explorer), true)
 	}
-	if err := screen.ShowText(line1, 1, display.LEFT); err != nil {
-		log.Printf("Error showing explorer path: %v", err)
-	}
-	if err := screen.ShowText(line2, 2, display.LEFT); err != nil {
-		log.Printf("Error showing explorer path: %v", err)
+
+	// flash the letter after a jump by initial
+	if explorer.Letter != "" {
+		showOverlay(screen, &Notification{Title: explorer.Letter, Duration: 600})
 	}
 
 	mu.Lock()
@@ -145,18 +257,60 @@ func handleExplorerUpdate(explorer *Explorer, screen *display.Display, allowDate
 		mu.Lock()
 		defer mu.Unlock()
 		*allowDateTime = true
-		if err := screen.Clear(); err != nil {
-			log.Printf("Error cleaning display: %v", err)
-		}
+		render(screen, true, screenLine{}, screenLine{})
 	})
 }
 
-func getExplorerLines(explorer *Explorer) (string, string) {
-	const displayWidth = 16
-	upArrow := "|"
-	downArrow := "|"
+func showScreen(screen *display.Display, layouts *Layouts, name string, data any, clear bool) {
+	line1, line2, err := layouts.Render(name, data)
+	if err != nil {
+		log.Printf("Error rendering %s screen: %v", name, err)
+		return
+	}
+	render(screen, clear,
+		screenLine{text: line1, align: display.LEFT},
+		screenLine{text: line2, align: display.LEFT})
+	if activeScreen != nil {
+		activeScreen.Set(name)
+	}
+}
 
-	var lines [2]string
+func isPlaying(state string) bool {
+	return state == "playing" || state == "paused"
+}
+
+type ExplorerRow struct {
+	Item
+	Selected bool
+}
+
+// Label is the row as shown by the default layout: "+" marks the selected
+// item and "-" marks directories.
+func (r ExplorerRow) Label() string {
+	if r.Name == "" {
+		return ""
+	}
+	prefix := " "
+	if r.Selected {
+		prefix = "+"
+	}
+	if r.IsDir {
+		return fmt.Sprintf("%s-%s", prefix, r.Name)
+	}
+	return prefix + r.Name
+}
+
+// ExplorerView is the data given to the explorer layout, with the two
+// visible rows of the current page.
+type ExplorerView struct {
+	*Explorer
+	Rows       [2]ExplorerRow
+	ScrollUp   bool
+	ScrollDown bool
+}
+
+func NewExplorerView(explorer *Explorer) *ExplorerView {
+	view := &ExplorerView{Explorer: explorer}
 
 	start := explorer.SelectedIndex / 2 * 2
 	end := start + 2
@@ -165,38 +319,13 @@ func getExplorerLines(explorer *Explorer) (string, string) {
 	}
 
 	for i := start; i < end; i++ {
-		item := explorer.Items[i]
-		itemName := item.Name
-		if item.IsDir {
-			itemName = fmt.Sprintf("-%s", item.Name)
+		view.Rows[i-start] = ExplorerRow{
+			Item:     explorer.Items[i],
+			Selected: i == explorer.SelectedIndex,
 		}
-		prefix := " "
-		if i == explorer.SelectedIndex {
-			prefix = "+"
-		}
-		line := fmt.Sprintf("%s%s", prefix, itemName)
-
-		if len(line) > displayWidth {
-			line = line[:displayWidth]
-		} else {
-			line = fmt.Sprintf("%-16s", line)
-		}
-
-		lines[i-start] = line
-	}
-
-	for i := range lines {
-		if len(lines[i]) == 0 {
-			lines[i] = strings.Repeat(" ", displayWidth)
-		}
-	}
-
-	if explorer.HasPrevious {
-		lines[0] = lines[0][:(displayWidth-1)] + upArrow
-	}
-	if explorer.HasNext && (start+1 < len(explorer.Items)) {
-		lines[1] = lines[1][:(displayWidth-1)] + downArrow
 	}
 
-	return lines[0], lines[1]
+	view.ScrollUp = explorer.HasPrevious
+	view.ScrollDown = explorer.HasNext && (start+1 < len(explorer.Items))
+	return view
 }
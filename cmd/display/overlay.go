@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"media-player/pkg/display"
+	"media-player/pkg/queue"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultOverlayDuration = 2 * time.Second
+
+// Time between checks of the Redis connection
+const redisCheckInterval = 3 * time.Second
+
+// Block character from the HD44780 character ROM, used to draw bars.
+const fullBlock = "\xff"
+
+type Notification struct {
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Text      string `json:"text"`
+	Level     int    `json:"level"`
+	Duration  int    `json:"duration_ms,omitempty"`
+}
+
+const (
+	NOTIFICATION_MESSAGE = "message"
+	NOTIFICATION_VOLUME  = "volume"
+	NOTIFICATION_ERROR   = "error"
+)
+
+type screenLine struct {
+	text  string
+	align display.Alignment
+}
+
+var (
+	overlayMu     sync.Mutex
+	overlayActive bool
+	overlayTimer  *time.Timer
+	baseLines     [2]screenLine
+)
+
+// render records the lines of the current screen and draws them, unless an
+// overlay is being shown. In that case they are drawn when the overlay ends.
+func render(screen *display.Display, clear bool, line1, line2 screenLine) {
+	overlayMu.Lock()
+	defer overlayMu.Unlock()
+
+	baseLines = [2]screenLine{line1, line2}
+	if overlayActive {
+		return
+	}
+	draw(screen, clear, baseLines)
+}
+
+func draw(screen *display.Display, clear bool, lines [2]screenLine) {
+	if clear {
+		if err := screen.Clear(); err != nil {
+			log.Printf("Error cleaning display: %v", err)
+		}
+	}
+	for i, line := range lines {
+		if line.text == "" {
+			continue
+		}
+		if err := screen.ShowText(line.text, i+1, line.align); err != nil {
+			log.Printf("Error showing line %d: %v", i+1, err)
+		}
+	}
+}
+
+func showOverlay(screen *display.Display, notification *Notification) {
+	line1, line2 := getOverlayLines(notification)
+
+	duration := defaultOverlayDuration
+	if notification.Duration > 0 {
+		duration = time.Duration(notification.Duration) * time.Millisecond
+	}
+
+	overlayMu.Lock()
+	defer overlayMu.Unlock()
+
+	overlayActive = true
+	draw(screen, true, [2]screenLine{
+		{text: line1, align: display.CENTER},
+		{text: line2, align: display.CENTER},
+	})
+
+	if overlayTimer != nil {
+		overlayTimer.Stop()
+	}
+
+	overlayTimer = time.AfterFunc(duration, func() {
+		overlayMu.Lock()
+		defer overlayMu.Unlock()
+		overlayActive = false
+		draw(screen, true, baseLines)
+	})
+}
+
+func getOverlayLines(notification *Notification) (string, string) {
+	switch notification.Type {
+	case NOTIFICATION_VOLUME:
+		title := notification.Title
+		if title == "" {
+			title = fmt.Sprintf("Volume %d%%", notification.Level)
+		}
+		return title, volumeBar(notification.Level)
+	case NOTIFICATION_ERROR:
+		title := notification.Title
+		if title == "" {
+			title = "Error"
+		}
+		return title, notification.Text
+	default:
+		return notification.Title, notification.Text
+	}
+}
+
+func volumeBar(level int) string {
+	if level < 0 {
+		level = 0
+	}
+	if level > 100 {
+		level = 100
+	}
+	filled := level * displayWidth / 100
+	return strings.Repeat(fullBlock, filled) + strings.Repeat(" ", displayWidth-filled)
+}
+
+// watchRedis shows an overlay while Redis can't be reached, since no
+// notification can arrive through it then. The overlay outlasts the check
+// interval, so it stays up until Redis is back.
+func watchRedis(ctx context.Context, q *queue.Queue, screen *display.Display) {
+	ticker := time.NewTicker(redisCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.Ping(); err != nil {
+				log.Printf("Redis unreachable: %v", err)
+				showOverlay(screen, &Notification{
+					Type:     NOTIFICATION_ERROR,
+					Title:    "Redis",
+					Text:     "reconnecting...",
+					Duration: int((redisCheckInterval + time.Second) / time.Millisecond),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"media-player/pkg/queue"
+	"sync"
+	"time"
+)
+
+const SCREEN_TOPIC = "screen"
+
+type ScreenState struct {
+	Timestamp string `json:"timestamp"`
+	Screen    string `json:"screen"`
+}
+
+// ActiveScreen tells the other services which screen is shown, so a key can
+// mean something different on each one.
+type ActiveScreen struct {
+	mu   sync.Mutex
+	q    *queue.Queue
+	name string
+}
+
+// Screen being shown, nil until the queue is ready
+var activeScreen *ActiveScreen
+
+func NewActiveScreen(q *queue.Queue) *ActiveScreen {
+	return &ActiveScreen{q: q}
+}
+
+// Set publishes name when it differs from the screen shown before.
+func (a *ActiveScreen) Set(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.name == name {
+		return
+	}
+	a.name = name
+
+	state := ScreenState{
+		Timestamp: time.Now().Format("02-01-2006T15:04:05.000"),
+		Screen:    name,
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("Error serializing ScreenState: %v", err)
+		return
+	}
+	if err := a.q.Publish(SCREEN_TOPIC, string(data)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", SCREEN_TOPIC, err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Deepest navigation remembered, older folders are dropped
+const HISTORY_LIMIT = 64
+
+// Position identifies a folder and the item selected in it.
+type Position struct {
+	Root     string `json:"root"`
+	Dir      string `json:"dir"`
+	Selected string `json:"selected"`
+}
+
+// Folders entered to reach the current one, the innermost last
+var history []Position
+
+func (e *Explorer) position() Position {
+	pos := Position{Root: e.Root, Dir: e.CurrentDir}
+	if len(e.Items) > 0 {
+		pos.Selected = e.Items[e.SelectedIndex].Path
+	}
+	return pos
+}
+
+// navigate shows fresh, remembering the current folder so Back returns to
+// the item it was entered from.
+func (e *Explorer) navigate(fresh *Explorer) {
+	history = append(history, e.position())
+	if len(history) > HISTORY_LIMIT {
+		history = history[len(history)-HISTORY_LIMIT:]
+	}
+	*e = *fresh
+}
+
+// selectPath selects the item with path, reporting whether it was found.
+func (e *Explorer) selectPath(path string) bool {
+	for i, item := range e.Items {
+		if item.Path == path {
+			e.SelectedIndex = i
+			return true
+		}
+	}
+	return false
+}
+
+// openPosition lists the folder of pos again, selecting the item it points
+// to.
+func openPosition(pos Position) (*Explorer, error) {
+	var explorer *Explorer
+	var err error
+
+	switch {
+	case isSearchPath(pos.Dir):
+		explorer = NewSearchResults(strings.TrimPrefix(pos.Dir, SEARCH_PREFIX))
+	case isStatePath(pos.Dir):
+		explorer = NewStateExplorer(pos.Dir)
+	case isLibraryPath(pos.Dir):
+		explorer, err = NewLibraryExplorer(pos.Dir)
+	case pos.Root == "":
+		explorer, err = NewRootMenu()
+	default:
+		root, ok := findRoot(pos.Root)
+		if !ok || !insideRoot(root, pos.Dir) {
+			return nil, fmt.Errorf("%s is outside of root %s", pos.Dir, pos.Root)
+		}
+		explorer, err = NewExplorer(root, pos.Dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	explorer.selectPath(pos.Selected)
+	return explorer, nil
+}
+
+// restoreNavigation reopens the folder shown before the last restart.
+func restoreNavigation() (*Explorer, bool) {
+	if state.Position == nil {
+		return nil, false
+	}
+	explorer, err := openPosition(*state.Position)
+	if err != nil {
+		log.Printf("Unable to restore %s: %v", state.Position.Dir, err)
+		return nil, false
+	}
+	history = state.History
+	return explorer, true
+}
+
+// withNavigation returns s with the current folder and the history, to be
+// restored after a restart.
+func withNavigation(s State, e *Explorer) State {
+	pos := e.position()
+	s.Position = &pos
+	s.History = append([]Position(nil), history...)
+	return s
+}
+
+// saveNavigation stores the navigation in the state file. It runs on
+// shutdown only; the navigation is also saved along with the recently played
+// tracks when a track starts, instead of writing the SD card on every move.
+func saveNavigation(e *Explorer) {
+	if err := saveState(withNavigation(state, e)); err != nil {
+		log.Printf("Error saving navigation: %v", err)
+	}
+}
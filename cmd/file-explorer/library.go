@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"media-player/pkg/library"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Virtual folders of the library are addressed as "library:/Artists/<artist>/<album>"
+const LIBRARY_PREFIX = "library:"
+const LIBRARY_ROOT = "Library"
+
+const (
+	VIEW_ARTISTS = "Artists"
+	VIEW_GENRES  = "Genres"
+	VIEW_YEARS   = "Years"
+	VIEW_ALL     = "All Tracks"
+)
+
+var libraryViews = []string{VIEW_ARTISTS, VIEW_GENRES, VIEW_YEARS, VIEW_ALL}
+
+type LibraryConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Path    string `toml:"path"`
+}
+
+// Tag index, nil when the library is disabled
+var libraryIndex *library.Index
+
+func isLibraryPath(path string) bool {
+	return strings.HasPrefix(path, LIBRARY_PREFIX)
+}
+
+func libraryPath(segments ...string) string {
+	path := LIBRARY_PREFIX
+	for _, segment := range segments {
+		path += "/" + url.PathEscape(segment)
+	}
+	return path
+}
+
+func splitLibraryPath(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimPrefix(path, LIBRARY_PREFIX), "/") {
+		if segment == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// libraryFilter selects the tracks below the virtual folder. leaf reports
+// whether the folder lists tracks instead of other folders.
+func libraryFilter(segments []string) (filter func(library.Track) bool, leaf bool, err error) {
+	if len(segments) == 0 {
+		return nil, false, fmt.Errorf("no library view")
+	}
+
+	view, args := segments[0], segments[1:]
+	switch {
+	case view == VIEW_ARTISTS && len(args) == 0:
+		return nil, false, nil
+	case view == VIEW_ARTISTS && len(args) == 1:
+		return func(t library.Track) bool { return t.Artist == args[0] }, false, nil
+	case view == VIEW_ARTISTS && len(args) == 2:
+		return func(t library.Track) bool { return t.Artist == args[0] && t.Album == args[1] }, true, nil
+	case view == VIEW_GENRES && len(args) == 0:
+		return nil, false, nil
+	case view == VIEW_GENRES && len(args) == 1:
+		return func(t library.Track) bool { return t.Genre == args[0] }, true, nil
+	case view == VIEW_YEARS && len(args) == 0:
+		return nil, false, nil
+	case view == VIEW_YEARS && len(args) == 1:
+		return func(t library.Track) bool { return t.YearName() == args[0] }, true, nil
+	case view == VIEW_ALL && len(args) == 0:
+		return nil, true, nil
+	}
+	return nil, false, fmt.Errorf("unknown library folder %s", strings.Join(segments, "/"))
+}
+
+// NewLibraryExplorer lists a virtual folder of the library.
+func NewLibraryExplorer(path string) (*Explorer, error) {
+	if libraryIndex == nil {
+		return nil, fmt.Errorf("library is disabled")
+	}
+
+	segments := splitLibraryPath(path)
+	filter, leaf, err := libraryFilter(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	if leaf {
+		for _, track := range libraryIndex.Find(filter) {
+			items = append(items, Item{IsDir: false, Name: track.Title, Path: track.Path})
+		}
+	} else {
+		var field func(library.Track) string
+		switch {
+		case segments[0] == VIEW_ARTISTS && len(segments) == 1:
+			field = func(t library.Track) string { return t.Artist }
+		case segments[0] == VIEW_ARTISTS:
+			field = func(t library.Track) string { return t.Album }
+		case segments[0] == VIEW_GENRES:
+			field = func(t library.Track) string { return t.Genre }
+		case segments[0] == VIEW_YEARS:
+			field = func(t library.Track) string { return t.YearName() }
+		}
+		for _, value := range libraryIndex.Values(field, filter) {
+			items = append(items, Item{
+				IsDir: true,
+				Name:  value,
+				Path:  libraryPath(append(segments, value)...),
+			})
+		}
+	}
+
+	explorer := &Explorer{
+		Root:       LIBRARY_ROOT,
+		CurrentDir: path,
+		SortMode:   sortMode,
+		Items:      items,
+	}
+	explorer.RefreshTimestamp()
+	return explorer, nil
+}
+
+// libraryParent returns the parent virtual folder, or false at the top of
+// a view.
+func libraryParent(path string) (string, bool) {
+	segments := splitLibraryPath(path)
+	if len(segments) <= 1 {
+		return "", false
+	}
+	return libraryPath(segments[:len(segments)-1]...), true
+}
+
+// libraryTracks returns every track below a virtual folder.
+func libraryTracks(path string) ([]string, error) {
+	if libraryIndex == nil {
+		return nil, fmt.Errorf("library is disabled")
+	}
+	filter, _, err := libraryFilter(splitLibraryPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []string
+	for _, track := range libraryIndex.Find(filter) {
+		tracks = append(tracks, track.Path)
+	}
+	return tracks, nil
+}
+
+// scanLibrary indexes the tags of every playable file in the roots.
+func scanLibrary(index *library.Index) {
+	for _, root := range roots {
+		if _, err := os.Stat(root.Path); err != nil {
+			continue
+		}
+		tracks, err := collectTracks(root, root.Path)
+		if err != nil {
+			log.Printf("Error scanning root %s: %v", root.Name, err)
+			continue
+		}
+		failed, err := index.Sync(root.Path, tracks)
+		if err != nil {
+			log.Printf("Error indexing root %s: %v", root.Name, err)
+			continue
+		}
+		for _, path := range failed {
+			log.Printf("Unable to index %s", path)
+		}
+	}
+	log.Printf("Library indexed: %d tracks", index.Len())
+}
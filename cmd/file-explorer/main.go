@@ -3,21 +3,30 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"media-player/pkg/config"
+	"media-player/pkg/library"
 	"media-player/pkg/queue"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
-const REMOTE_CONTROL_TOPIC = "remote-control"
+const ACTIONS_TOPIC = "actions"
 const FILE_EXPLORER_TOPIC = "file-explorer"
 
 const ROOT_PATH = "/opt/file-explorer-root"
 
+// Guards the explorer, changed both by commands and by the file watcher
+var explorerMu sync.Mutex
+
 type Item struct {
 	IsDir bool   `json:"is_dir"`
 	Name  string `json:"name"`
@@ -25,44 +34,41 @@ type Item struct {
 }
 
 type Explorer struct {
-	Timestamp     string `json:"timestamp"`
-	CurrentDir    string `json:"current_dir"`
-	HasNext       bool   `json:"has_next`
-	HasPrevious   bool   `json:"has_previous`
-	SelectedIndex int    `json:"selected_index"`
-	Items         []Item `json:"items"`
+	Timestamp     string   `json:"timestamp"`
+	Root          string   `json:"root"`
+	CurrentDir    string   `json:"current_dir"`
+	SortMode      SortMode `json:"sort_mode"`
+	Letter        string   `json:"letter,omitempty"`
+	HasNext       bool     `json:"has_next"`
+	HasPrevious   bool     `json:"has_previous"`
+	SelectedIndex int      `json:"selected_index"`
+	Items         []Item   `json:"items"`
+	Search        *Search  `json:"search,omitempty"`
 }
 
-type CommandData struct {
+type ActionData struct {
 	Timestamp string `json:"timestamp"`
+	Action    string `json:"action"`
 	Key       string `json:"key"`
+	Context   string `json:"context"`
+	// Number typed on the remote, from 1
+	Number int `json:"number,omitempty"`
+	// Argument set by a macro, e.g. the root opened by explorer.root
+	Value string `json:"value,omitempty"`
 }
 
-func NewExplorer(startDir string) (*Explorer, error) {
-	entries, err := os.ReadDir(startDir)
+func NewExplorer(root Root, startDir string) (*Explorer, error) {
+	items, err := listDir(root, startDir)
 	if err != nil {
 		return nil, err
 	}
-
-	var items []Item
-
-	for _, entry := range entries {
-		name := entry.Name()
-		fullPath := filepath.Join(startDir, entry.Name())
-
-		if entry.IsDir() || filepath.Ext(name) == ".mp3" {
-			item := Item{
-				IsDir: entry.IsDir(),
-				Name:  name,
-				Path:  fullPath,
-			}
-			items = append(items, item)
-		}
-	}
+	sortItems(items, sortMode)
 
 	return &Explorer{
 		Timestamp:     time.Now().Format("02-01-2006T15:04:05.000"),
+		Root:          root.Name,
 		CurrentDir:    startDir,
+		SortMode:      sortMode,
 		Items:         items,
 		SelectedIndex: 0,
 		HasNext:       false,
@@ -71,10 +77,57 @@ func NewExplorer(startDir string) (*Explorer, error) {
 }
 
 func main() {
-	explorer, err := NewExplorer(ROOT_PATH)
+	configPath := flag.String("config", config.Dir+"/file-explorer.toml", "configuration file")
+	flag.Parse()
+
+	cfg := Config{
+		Library:   LibraryConfig{Path: "/var/lib/media-player/library.db"},
+		StatePath: "/var/lib/media-player/file-explorer.json",
+	}
+	if err := config.Load(*configPath, &cfg); err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	roots = cfg.Roots
+	showHidden = cfg.ShowHidden
+	if cfg.PageSize > 0 {
+		pageSize = cfg.PageSize
+	}
+	if cfg.Recent > 0 {
+		recentLimit = cfg.Recent
+	}
+	if err := loadState(cfg.StatePath); err != nil {
+		log.Printf("Error loading state, starting empty: %v", err)
+	}
+	if cfg.Sort != "" {
+		if !validSortMode(cfg.Sort) {
+			log.Fatalf("Invalid sort mode: %s", cfg.Sort)
+		}
+		sortMode = cfg.Sort
+	}
+	if len(roots) == 0 {
+		roots = []Root{{Name: "Music", Path: ROOT_PATH}}
+	}
+	for i := range roots {
+		roots[i].Path = filepath.Clean(roots[i].Path)
+	}
+
+	if cfg.Library.Enabled {
+		index, err := library.Open(cfg.Library.Path)
+		if err != nil {
+			log.Fatalf("Error opening library index: %v", err)
+		}
+		defer index.Close()
+		libraryIndex = index
+		go scanLibrary(index)
+	}
+
+	explorer, err := NewRootMenu()
 	if err != nil {
 		fmt.Println(err)
 	}
+	if restored, ok := restoreNavigation(); ok {
+		explorer = restored
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -83,8 +136,20 @@ func main() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGABRT)
 
 	q := queue.NewQueue()
+
+	watcher, err := NewWatcher(libraryIndex, handleFileChanges(q, explorer))
+	if err != nil {
+		log.Fatalf("Error creating file watcher: %v", err)
+	}
+	for _, root := range roots {
+		if err := watcher.AddRoot(root); err != nil {
+			log.Printf("Unable to watch root %s, retrying later: %v", root.Name, err)
+		}
+	}
+	go watcher.Run(ctx.Done())
+
 	go func() {
-		if err := q.Subscribe(ctx, handleCommand(q, explorer), REMOTE_CONTROL_TOPIC); err != nil {
+		if err := q.Subscribe(ctx, handleCommand(q, explorer), ACTIONS_TOPIC, PLAYER_TOPIC); err != nil {
 			fmt.Printf("Error subscribing to topic: %v\n", err)
 			cancel()
 		}
@@ -93,6 +158,9 @@ func main() {
 	go func() {
 		<-sigChan
 		log.Println("Stopping service...")
+		explorerMu.Lock()
+		saveNavigation(explorer)
+		explorerMu.Unlock()
 		cancel()
 	}()
 	<-ctx.Done()
@@ -114,6 +182,78 @@ func (e *Explorer) Previous() {
 	}
 }
 
+// CycleSort switches to the next sort mode, keeping the selected item.
+func (e *Explorer) CycleSort() {
+	sortMode = nextSortMode(sortMode)
+	e.SortMode = sortMode
+	if e.IsRootMenu() || len(e.Items) == 0 {
+		return
+	}
+
+	selected := e.Items[e.SelectedIndex].Path
+	sortItems(e.Items, sortMode)
+	for i, item := range e.Items {
+		if item.Path == selected {
+			e.SelectedIndex = i
+			break
+		}
+	}
+}
+
+// Refresh lists the current folder again, keeping the selected item. When
+// the folder is gone, the closest existing parent is shown.
+func (e *Explorer) Refresh() error {
+	var fresh *Explorer
+	var err error
+
+	switch {
+	case e.Search != nil:
+		if libraryIndex == nil {
+			e.Search.candidates = searchCandidates()
+		}
+		e.updateSearch()
+		return nil
+	case isSearchPath(e.CurrentDir):
+		fresh = NewSearchResults(strings.TrimPrefix(e.CurrentDir, SEARCH_PREFIX))
+	case isStatePath(e.CurrentDir):
+		fresh = NewStateExplorer(e.CurrentDir)
+	case e.IsRootMenu():
+		fresh, err = NewRootMenu()
+	case isLibraryPath(e.CurrentDir):
+		fresh, err = NewLibraryExplorer(e.CurrentDir)
+	default:
+		root, _ := findRoot(e.Root)
+		dir := e.CurrentDir
+		for {
+			fresh, err = NewExplorer(root, dir)
+			if err == nil || dir == root.Path || !insideRoot(root, dir) {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		if err != nil {
+			fresh, err = NewRootMenu()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if fresh.CurrentDir == e.CurrentDir && len(e.Items) > 0 {
+		selected := e.Items[e.SelectedIndex].Path
+		fresh.SelectedIndex = min(e.SelectedIndex, max(len(fresh.Items)-1, 0))
+		for i, item := range fresh.Items {
+			if item.Path == selected {
+				fresh.SelectedIndex = i
+				break
+			}
+		}
+	}
+	fresh.Letter = ""
+	*e = *fresh
+	return nil
+}
+
 func (e *Explorer) Enter() error {
 	if len(e.Items) == 0 {
 		return nil
@@ -121,53 +261,286 @@ func (e *Explorer) Enter() error {
 
 	selected := e.Items[e.SelectedIndex]
 
+	if isSearchPath(selected.Path) {
+		e.navigate(NewSearchExplorer(""))
+		return nil
+	}
+
+	if isStatePath(selected.Path) {
+		e.navigate(NewStateExplorer(selected.Path))
+		return nil
+	}
+
+	if isLibraryPath(selected.Path) {
+		newExplorer, err := NewLibraryExplorer(selected.Path)
+		if err != nil {
+			return err
+		}
+		e.navigate(newExplorer)
+		return nil
+	}
+
+	// favorite folders may come from any root
+	if isStatePath(e.CurrentDir) {
+		if !selected.IsDir {
+			return nil
+		}
+		root, ok := rootFor(selected.Path)
+		if !ok {
+			return fmt.Errorf("%s is outside of the roots", selected.Path)
+		}
+		newExplorer, err := NewExplorer(root, selected.Path)
+		if err != nil {
+			return err
+		}
+		e.navigate(newExplorer)
+		return nil
+	}
+
+	if e.IsRootMenu() {
+		root, ok := findRoot(selected.Name)
+		if !ok {
+			return fmt.Errorf("unknown root %s", selected.Name)
+		}
+		newExplorer, err := NewExplorer(root, root.Path)
+		if err != nil {
+			return err
+		}
+		e.navigate(newExplorer)
+		return nil
+	}
+
 	if e.Items[e.SelectedIndex].IsDir {
+		root, _ := findRoot(e.Root)
 		fullPath := filepath.Join(e.CurrentDir, selected.Name)
-		newExplorer, err := NewExplorer(fullPath)
+		real, err := resolveInRoot(root, fullPath)
 		if err != nil {
 			return err
 		}
-		*e = *newExplorer
-	} else {
-		fmt.Println("Playing music")
+		if isCycle(root, e.CurrentDir, real) {
+			return fmt.Errorf("%s leads back to one of its parents", fullPath)
+		}
+		newExplorer, err := NewExplorer(root, fullPath)
+		if err != nil {
+			return err
+		}
+		e.navigate(newExplorer)
 	}
 	return nil
 }
 
+// Back returns to the folder the current one was entered from, selecting the
+// item that was opened. Without history, e.g. after a restored folder was
+// removed, the parent folder is shown instead.
 func (e *Explorer) Back() error {
-	if e.CurrentDir == ROOT_PATH {
+	if isSearchPath(e.CurrentDir) && e.Search == nil {
+		*e = *NewSearchExplorer(strings.TrimPrefix(e.CurrentDir, SEARCH_PREFIX))
 		return nil
 	}
-	parent := filepath.Dir(e.CurrentDir)
-	newExplorer, err := NewExplorer(parent)
-	if err != nil {
+
+	for len(history) > 0 {
+		pos := history[len(history)-1]
+		history = history[:len(history)-1]
+		previous, err := openPosition(pos)
+		if err != nil {
+			// the folder is gone, try the one before it
+			log.Printf("Unable to return to %s: %v", pos.Dir, err)
+			continue
+		}
+		*e = *previous
+		return nil
+	}
+
+	parent, err := e.parent()
+	if err != nil || parent == nil {
 		return err
 	}
-	*e = *newExplorer
+	parent.selectPath(e.CurrentDir)
+	*e = *parent
 	return nil
 }
 
+// parent lists the folder above the current one, or returns nil at the
+// root menu.
+func (e *Explorer) parent() (*Explorer, error) {
+	if e.IsRootMenu() {
+		return nil, nil
+	}
+	if isLibraryPath(e.CurrentDir) {
+		if parent, ok := libraryParent(e.CurrentDir); ok {
+			return NewLibraryExplorer(parent)
+		}
+		return NewRootMenu()
+	}
+	if e.Search != nil || isStatePath(e.CurrentDir) {
+		return NewRootMenu()
+	}
+	root, _ := findRoot(e.Root)
+	if e.CurrentDir == root.Path {
+		return NewRootMenu()
+	}
+	parent := filepath.Dir(e.CurrentDir)
+	if !insideRoot(root, parent) {
+		return nil, fmt.Errorf("%s is outside of root %s", parent, root.Name)
+	}
+	return NewExplorer(root, parent)
+}
+
+// handleFileChanges republishes the explorer when the folder being shown,
+// or the library, changed on disk.
+func handleFileChanges(q *queue.Queue, explorer *Explorer) func(dirs []string) {
+	return func(dirs []string) {
+		explorerMu.Lock()
+		defer explorerMu.Unlock()
+
+		affected := isLibraryPath(explorer.CurrentDir) || isSearchPath(explorer.CurrentDir) ||
+			isStatePath(explorer.CurrentDir)
+		for _, dir := range dirs {
+			if !explorer.IsRootMenu() && within(dir, explorer.CurrentDir) {
+				affected = true
+			}
+		}
+		if !affected {
+			return
+		}
+
+		if err := explorer.Refresh(); err != nil {
+			log.Printf("Error refreshing explorer: %v", err)
+			return
+		}
+		publish(explorer, q)
+	}
+}
+
+// handlePlayerData adds every track that starts playing to the recently
+// played list.
+func handlePlayerData(q *queue.Queue, explorer *Explorer, message string) {
+	var data PlayerData
+	if err := json.Unmarshal([]byte(message), &data); err != nil {
+		log.Printf("Error parsing JSON for PlayerData: %v", err)
+		return
+	}
+	if data.Media.State != "playing" || data.Media.Data.Path == "" {
+		return
+	}
+
+	explorerMu.Lock()
+	defer explorerMu.Unlock()
+
+	if len(state.Recent) > 0 && state.Recent[0].Path == data.Media.Data.Path {
+		return
+	}
+	state = withNavigation(state, explorer)
+	if err := addRecent(recentItem(data.Media.Data.Path, data.Media.Data.Title)); err != nil {
+		log.Printf("Error saving recently played: %v", err)
+	}
+	if explorer.CurrentDir == RECENT_PATH {
+		if err := explorer.Refresh(); err != nil {
+			log.Printf("Error refreshing explorer: %v", err)
+			return
+		}
+		publish(explorer, q)
+	}
+}
+
 func handleCommand(q *queue.Queue, explorer *Explorer) func(topic, message string) {
 	return func(topic, message string) {
-		var command CommandData
-		if err := json.Unmarshal([]byte(message), &command); err != nil {
-			log.Printf("Error parsing JSON for CommandData: %v", err)
+		if topic == PLAYER_TOPIC {
+			handlePlayerData(q, explorer, message)
 			return
 		}
 
-		switch command.Key {
-		case "KEY_DOWN":
+		explorerMu.Lock()
+		defer explorerMu.Unlock()
+
+		var action ActionData
+		if err := json.Unmarshal([]byte(message), &action); err != nil {
+			log.Printf("Error parsing JSON for ActionData: %v", err)
+			return
+		}
+
+		explorer.Letter = ""
+
+		// sent by macros, so it works from any screen, the search included
+		if action.Action == "explorer.root" {
+			if err := explorer.OpenRoot(action.Value); err != nil {
+				fmt.Println("Error on root:", err)
+				notify(q, "error", "Unknown folder", action.Value)
+				return
+			}
+			publish(explorer, q)
+			return
+		}
+
+		if explorer.Search != nil {
+			if err := explorer.SearchAction(action.Action); err != nil {
+				fmt.Println("Error on search:", err)
+			}
+			publish(explorer, q)
+			return
+		}
+
+		switch action.Action {
+		case "nav.page_down":
+			explorer.PageDown()
+			publish(explorer, q)
+		case "nav.page_up":
+			explorer.PageUp()
+			publish(explorer, q)
+		case "nav.right":
+			explorer.NextLetter()
+			publish(explorer, q)
+		case "nav.left":
+			explorer.PreviousLetter()
+			publish(explorer, q)
+		case "nav.down":
 			explorer.Next()
 			publish(explorer, q)
-		case "KEY_UP":
+		case "nav.up":
 			explorer.Previous()
 			publish(explorer, q)
-		case "KEY_OK":
+		case "nav.ok":
+			if len(explorer.Items) > 0 && !explorer.Items[explorer.SelectedIndex].IsDir {
+				explorer.PlaySelected(q)
+				return
+			}
 			if err := explorer.Enter(); err != nil {
 				fmt.Println("Error on enter:", err)
 			}
 			publish(explorer, q)
-		case "KEY_BACKSPACE":
+		case "explorer.select":
+			if !explorer.SelectNumber(action.Number) {
+				notify(q, "message", "No item", strconv.Itoa(action.Number))
+				return
+			}
+			publish(explorer, q)
+		case "explorer.sort":
+			explorer.CycleSort()
+			notify(q, "message", "Sort", string(explorer.SortMode))
+			publish(explorer, q)
+		case "explorer.enqueue":
+			if err := explorer.EnqueueSelected(q); err != nil {
+				fmt.Println("Error on enqueue:", err)
+			}
+		case "explorer.favorite":
+			added, err := explorer.ToggleFavorite()
+			if err != nil {
+				fmt.Println("Error on favorite:", err)
+				return
+			}
+			if added {
+				notify(q, "message", "Favorites", "Added")
+			} else {
+				notify(q, "message", "Favorites", "Removed")
+			}
+			if explorer.CurrentDir == FAVORITES_PATH {
+				if err := explorer.Refresh(); err != nil {
+					log.Printf("Error refreshing explorer: %v", err)
+					return
+				}
+				publish(explorer, q)
+			}
+		case "nav.back":
 			if err := explorer.Back(); err != nil {
 				fmt.Println("Error on back:", err)
 			}
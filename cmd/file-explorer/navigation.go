@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Rows shown at once by the display, used as the page size
+var pageSize = 2
+
+func (e *Explorer) PageDown() {
+	e.SelectedIndex += pageSize
+	if e.SelectedIndex > len(e.Items)-1 {
+		e.SelectedIndex = len(e.Items) - 1
+	}
+	if e.SelectedIndex < 0 {
+		e.SelectedIndex = 0
+	}
+}
+
+func (e *Explorer) PageUp() {
+	e.SelectedIndex -= pageSize
+	if e.SelectedIndex < 0 {
+		e.SelectedIndex = 0
+	}
+}
+
+// SelectNumber selects the item numbered n, counting from 1, returning
+// false when there is no such item.
+func (e *Explorer) SelectNumber(n int) bool {
+	if n < 1 || n > len(e.Items) {
+		return false
+	}
+	e.SelectedIndex = n - 1
+	return true
+}
+
+// NextLetter selects the first item whose initial differs from the selected
+// one.
+func (e *Explorer) NextLetter() {
+	if len(e.Items) == 0 {
+		return
+	}
+	current := initial(e.Items[e.SelectedIndex].Name)
+	for i := e.SelectedIndex + 1; i < len(e.Items); i++ {
+		if initial(e.Items[i].Name) != current {
+			e.SelectedIndex = i
+			break
+		}
+	}
+	e.Letter = initial(e.Items[e.SelectedIndex].Name)
+}
+
+// PreviousLetter selects the first item of the previous initial, or of the
+// current one when the selection is in the middle of it.
+func (e *Explorer) PreviousLetter() {
+	if len(e.Items) == 0 {
+		return
+	}
+	i := e.SelectedIndex
+	current := initial(e.Items[i].Name)
+	if i > 0 && initial(e.Items[i-1].Name) == current {
+		for i > 0 && initial(e.Items[i-1].Name) == current {
+			i--
+		}
+	} else if i > 0 {
+		i--
+		previous := initial(e.Items[i].Name)
+		for i > 0 && initial(e.Items[i-1].Name) == previous {
+			i--
+		}
+	}
+	e.SelectedIndex = i
+	e.Letter = initial(e.Items[i].Name)
+}
+
+// initial is the uppercase first letter of name, or "#" for anything else.
+func initial(name string) string {
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			return strings.ToUpper(string(r))
+		}
+		return "#"
+	}
+	return "#"
+}
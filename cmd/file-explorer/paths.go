@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveInRoot follows the symlinks of path and checks that the real
+// location is still inside the root, returning it.
+func resolveInRoot(root Root, path string) (string, error) {
+	realRoot, err := filepath.EvalSymlinks(root.Path)
+	if err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	if !within(realRoot, real) {
+		return "", fmt.Errorf("%s leads outside of root %s", path, root.Name)
+	}
+	return real, nil
+}
+
+// isCycle reports whether real is the real location of dir or of any of its
+// parents up to the root, meaning a symlink that loops back.
+func isCycle(root Root, dir string, real string) bool {
+	for p := dir; insideRoot(root, p); p = filepath.Dir(p) {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil && resolved == real {
+			return true
+		}
+		if p == root.Path {
+			break
+		}
+	}
+	return false
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// listDir returns the folders and playable files of dir. Symlinks are
+// followed only when they stay inside the root and do not loop back.
+func listDir(root Root, dir string) ([]Item, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+
+	for _, entry := range entries {
+		name := entry.Name()
+		fullPath := filepath.Join(dir, name)
+
+		if !showHidden && isHidden(name) {
+			continue
+		}
+
+		isDir := entry.IsDir()
+		if entry.Type()&fs.ModeSymlink != 0 {
+			real, err := resolveInRoot(root, fullPath)
+			if err != nil {
+				log.Printf("Skipping %s: %v", fullPath, err)
+				continue
+			}
+			info, err := os.Stat(real)
+			if err != nil {
+				log.Printf("Skipping %s: %v", fullPath, err)
+				continue
+			}
+			isDir = info.IsDir()
+			if isDir && isCycle(root, dir, real) {
+				log.Printf("Skipping %s: symlink loop", fullPath)
+				continue
+			}
+		}
+
+		if isDir || isPlayable(name) {
+			items = append(items, Item{
+				IsDir: isDir,
+				Name:  name,
+				Path:  fullPath,
+			})
+		}
+	}
+
+	return items, nil
+}
+
+// collectTracks walks dir recursively, in lexical order, returning the
+// playable files. Each real folder is visited only once.
+func collectTracks(root Root, dir string) ([]string, error) {
+	var tracks []string
+	visited := map[string]bool{}
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		real, err := resolveInRoot(root, dir)
+		if err != nil {
+			return err
+		}
+		if visited[real] {
+			return nil
+		}
+		visited[real] = true
+
+		items, err := listDir(root, dir)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if !item.IsDir {
+				tracks = append(tracks, item.Path)
+				continue
+			}
+			if err := walk(item.Path); err != nil {
+				log.Printf("Skipping %s: %v", item.Path, err)
+			}
+		}
+		return nil
+	}
+
+	err := walk(dir)
+	return tracks, err
+}
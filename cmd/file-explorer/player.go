@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"media-player/pkg/queue"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const PLAYER_COMMAND_TOPIC = "player-command"
+const NOTIFICATION_TOPIC = "notification"
+const PLAYER_TOPIC = "player"
+
+type PlayerCommand struct {
+	Timestamp string   `json:"timestamp"`
+	Action    string   `json:"action"`
+	Tracks    []string `json:"tracks,omitempty"`
+	Index     int      `json:"index,omitempty"`
+}
+
+// PlayerData is the part of the player state used to feed the recently
+// played list.
+type PlayerData struct {
+	Media struct {
+		State string `json:"state"`
+		Data  struct {
+			Title string `json:"title"`
+			Path  string `json:"path"`
+		} `json:"data"`
+	} `json:"media"`
+}
+
+type Notification struct {
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Text      string `json:"text"`
+}
+
+func isPlayable(name string) bool {
+	return strings.ToLower(filepath.Ext(name)) == ".mp3"
+}
+
+// PlaySelected loads every file of the current directory in the player,
+// starting at the selected one.
+func (e *Explorer) PlaySelected(q *queue.Queue) {
+	if len(e.Items) == 0 {
+		return
+	}
+	selected := e.Items[e.SelectedIndex]
+
+	var tracks []string
+	index := 0
+	for _, item := range e.Items {
+		if item.IsDir {
+			continue
+		}
+		if item.Path == selected.Path {
+			index = len(tracks)
+		}
+		tracks = append(tracks, item.Path)
+	}
+	if len(tracks) == 0 {
+		return
+	}
+
+	sendPlayerCommand(q, PlayerCommand{Action: "load", Tracks: tracks, Index: index})
+	sendPlayerCommand(q, PlayerCommand{Action: "play"})
+}
+
+// EnqueueSelected adds the selected file, or every playable file below the
+// selected folder in sorted order, to the end of the player queue.
+func (e *Explorer) EnqueueSelected(q *queue.Queue) error {
+	if len(e.Items) == 0 {
+		return nil
+	}
+	selected := e.Items[e.SelectedIndex]
+
+	if !selected.IsDir {
+		sendPlayerCommand(q, PlayerCommand{Action: "enqueue", Tracks: []string{selected.Path}})
+		return nil
+	}
+
+	if isSearchPath(selected.Path) || isStatePath(selected.Path) {
+		return nil
+	}
+
+	if isLibraryPath(selected.Path) {
+		tracks, err := libraryTracks(selected.Path)
+		if err != nil {
+			return err
+		}
+		if len(tracks) > 0 {
+			sendPlayerCommand(q, PlayerCommand{Action: "enqueue", Tracks: tracks})
+		}
+		return nil
+	}
+
+	root, ok := rootFor(selected.Path)
+	if !ok {
+		return fmt.Errorf("unknown root for %s", selected.Path)
+	}
+
+	tracks, err := collectTracks(root, selected.Path)
+	if err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		return nil
+	}
+	sendPlayerCommand(q, PlayerCommand{Action: "enqueue", Tracks: tracks})
+	return nil
+}
+
+func notify(q *queue.Queue, tp string, title string, text string) {
+	notification := Notification{
+		Timestamp: time.Now().Format("02-01-2006T15:04:05.000"),
+		Type:      tp,
+		Title:     title,
+		Text:      text,
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Error serializing Notification: %v", err)
+		return
+	}
+
+	if err := q.Publish(NOTIFICATION_TOPIC, string(data)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", NOTIFICATION_TOPIC, err)
+	}
+}
+
+func sendPlayerCommand(q *queue.Queue, command PlayerCommand) {
+	command.Timestamp = time.Now().Format("02-01-2006T15:04:05.000")
+
+	data, err := json.Marshal(command)
+	if err != nil {
+		log.Printf("Error serializing PlayerCommand: %v", err)
+		return
+	}
+
+	if err := q.Publish(PLAYER_COMMAND_TOPIC, string(data)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", PLAYER_COMMAND_TOPIC, err)
+	}
+}
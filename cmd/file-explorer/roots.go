@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Root struct {
+	Name string `toml:"name"`
+	Path string `toml:"path"`
+}
+
+type Config struct {
+	Roots      []Root        `toml:"roots"`
+	Library    LibraryConfig `toml:"library"`
+	ShowHidden bool          `toml:"show_hidden"`
+	Sort       SortMode      `toml:"sort"`
+	PageSize   int           `toml:"page_size"`
+	StatePath  string        `toml:"state_path"`
+	Recent     int           `toml:"recent"`
+}
+
+// Library roots loaded from the configuration
+var roots []Root
+
+// Whether dot-files and dot-folders are listed
+var showHidden bool
+
+func findRoot(name string) (Root, bool) {
+	for _, root := range roots {
+		if root.Name == name {
+			return root, true
+		}
+	}
+	return Root{}, false
+}
+
+// insideRoot reports whether path is the root directory or below it.
+func insideRoot(root Root, path string) bool {
+	return within(root.Path, path)
+}
+
+func within(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// NewRootMenu lists the available roots, the search, the favorites, the
+// recently played tracks and the library views as folders.
+func NewRootMenu() (*Explorer, error) {
+	var items []Item
+	for _, root := range roots {
+		// unmounted sources, like an USB stick, are hidden
+		if _, err := os.Stat(root.Path); err != nil {
+			continue
+		}
+		items = append(items, Item{
+			IsDir: true,
+			Name:  root.Name,
+			Path:  root.Path,
+		})
+	}
+	items = append(items,
+		Item{IsDir: true, Name: SEARCH_ROOT, Path: searchPath("")},
+		Item{IsDir: true, Name: FAVORITES_ROOT, Path: FAVORITES_PATH},
+		Item{IsDir: true, Name: RECENT_ROOT, Path: RECENT_PATH},
+	)
+	if libraryIndex != nil {
+		for _, view := range libraryViews {
+			items = append(items, Item{
+				IsDir: true,
+				Name:  view,
+				Path:  libraryPath(view),
+			})
+		}
+	}
+
+	explorer := &Explorer{
+		CurrentDir: "",
+		SortMode:   sortMode,
+		Items:      items,
+	}
+	explorer.RefreshTimestamp()
+	return explorer, nil
+}
+
+// OpenRoot shows the top folder of the root called name, as if it was
+// entered from the root menu.
+func (e *Explorer) OpenRoot(name string) error {
+	root, ok := findRoot(name)
+	if !ok {
+		return fmt.Errorf("unknown root %s", name)
+	}
+	newExplorer, err := NewExplorer(root, root.Path)
+	if err != nil {
+		return err
+	}
+	e.navigate(newExplorer)
+	return nil
+}
+
+func (e *Explorer) IsRootMenu() bool {
+	return e.Root == ""
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"log"
+	"media-player/pkg/library"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Search results are addressed as "search:<query>"
+const SEARCH_PREFIX = "search:"
+const SEARCH_ROOT = "Search"
+
+// Maximum number of results listed
+const SEARCH_LIMIT = 100
+
+// Characters offered by the picker, in the order nav.down walks them
+const searchWheel = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+
+// Search is the state of the character picker. The query typed so far plus
+// the character under the cursor are matched live.
+type Search struct {
+	Query string `json:"query"`
+	Char  string `json:"char"`
+	// position of Char in the wheel, -1 when no character is picked
+	wheel int
+	last  int
+	// playable files of the roots, matched by name when there is no index
+	candidates []Item
+}
+
+func isSearchPath(path string) bool {
+	return strings.HasPrefix(path, SEARCH_PREFIX)
+}
+
+func searchPath(query string) string {
+	return SEARCH_PREFIX + query
+}
+
+// NewSearchExplorer opens the character picker with query already typed.
+func NewSearchExplorer(query string) *Explorer {
+	search := &Search{Query: query, wheel: -1}
+	if libraryIndex == nil {
+		search.candidates = searchCandidates()
+	}
+
+	explorer := &Explorer{
+		Root:     SEARCH_ROOT,
+		SortMode: sortMode,
+		Search:   search,
+	}
+	explorer.updateSearch()
+	return explorer
+}
+
+// NewSearchResults lists the results of query as a folder.
+func NewSearchResults(query string) *Explorer {
+	var candidates []Item
+	if libraryIndex == nil {
+		candidates = searchCandidates()
+	}
+
+	explorer := &Explorer{
+		Root:       SEARCH_ROOT,
+		CurrentDir: searchPath(query),
+		SortMode:   sortMode,
+		Items:      searchItems(query, candidates),
+	}
+	explorer.RefreshTimestamp()
+	return explorer
+}
+
+// SearchAction handles an action while the picker is open: nav.up and
+// nav.down turn the wheel, nav.right accepts the character, nav.left
+// erases, nav.ok opens the results and nav.back leaves the search.
+func (e *Explorer) SearchAction(action string) error {
+	search := e.Search
+
+	switch action {
+	case "nav.down":
+		search.turn(1)
+	case "nav.up":
+		search.turn(-1)
+	case "nav.right":
+		if search.wheel < 0 {
+			return nil
+		}
+		search.Query += search.Char
+		search.last = search.wheel
+		search.wheel = -1
+	case "nav.left":
+		if search.wheel >= 0 {
+			search.wheel = -1
+		} else if search.Query != "" {
+			_, size := utf8.DecodeLastRuneInString(search.Query)
+			search.Query = search.Query[:len(search.Query)-size]
+		}
+	case "nav.ok":
+		query := search.Query + search.Char
+		if strings.TrimSpace(query) == "" {
+			return nil
+		}
+		*e = *NewSearchResults(query)
+		return nil
+	case "nav.back":
+		return e.Back()
+	default:
+		return nil
+	}
+
+	e.updateSearch()
+	return nil
+}
+
+// turn moves the wheel by step, starting from the last accepted character.
+func (s *Search) turn(step int) {
+	size := len(searchWheel)
+	if s.wheel < 0 {
+		s.wheel = s.last
+		if step < 0 {
+			s.wheel = (s.last - 1 + size) % size
+		}
+	} else {
+		s.wheel = (s.wheel + step + size) % size
+	}
+}
+
+func (e *Explorer) updateSearch() {
+	search := e.Search
+	search.Char = ""
+	if search.wheel >= 0 {
+		search.Char = searchWheel[search.wheel : search.wheel+1]
+	}
+
+	query := search.Query + search.Char
+	e.CurrentDir = searchPath(query)
+	e.Items = searchItems(query, search.candidates)
+	e.SelectedIndex = 0
+}
+
+// searchItems returns the artists and tracks whose file name or tags
+// contain query, ignoring case. Without the tag index only the file names
+// of candidates are matched.
+func searchItems(query string, candidates []Item) []Item {
+	query = strings.ToLower(query)
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+	matches := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), query)
+	}
+
+	var items []Item
+	if libraryIndex == nil {
+		for _, item := range candidates {
+			if matches(item.Name) {
+				items = append(items, item)
+			}
+		}
+	} else {
+		artists := libraryIndex.Values(
+			func(t library.Track) string { return t.Artist },
+			func(t library.Track) bool { return matches(t.Artist) },
+		)
+		for _, artist := range artists {
+			items = append(items, Item{IsDir: true, Name: artist, Path: libraryPath(VIEW_ARTISTS, artist)})
+		}
+
+		tracks := libraryIndex.Find(func(t library.Track) bool {
+			return matches(t.Title) || matches(t.Artist) || matches(t.Album) ||
+				matches(t.Genre) || matches(filepath.Base(t.Path))
+		})
+		for _, track := range tracks {
+			items = append(items, Item{IsDir: false, Name: track.Title, Path: track.Path})
+		}
+	}
+
+	if len(items) > SEARCH_LIMIT {
+		items = items[:SEARCH_LIMIT]
+	}
+	return items
+}
+
+// searchCandidates lists the playable files of every root.
+func searchCandidates() []Item {
+	var items []Item
+	for _, root := range roots {
+		if _, err := os.Stat(root.Path); err != nil {
+			continue
+		}
+		tracks, err := collectTracks(root, root.Path)
+		if err != nil {
+			log.Printf("Error listing root %s: %v", root.Name, err)
+			continue
+		}
+		for _, track := range tracks {
+			items = append(items, Item{IsDir: false, Name: filepath.Base(track), Path: track})
+		}
+	}
+	return items
+}
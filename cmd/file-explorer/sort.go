@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dhowden/tag"
+)
+
+type SortMode string
+
+const (
+	SORT_DIRS_FIRST SortMode = "dirs-first"
+	SORT_NATURAL    SortMode = "natural"
+	SORT_NAME       SortMode = "name"
+	SORT_MODIFIED   SortMode = "modified"
+	SORT_TRACK      SortMode = "track"
+)
+
+// Order in which the sort key cycles through the modes
+var sortModes = []SortMode{SORT_DIRS_FIRST, SORT_NATURAL, SORT_NAME, SORT_MODIFIED, SORT_TRACK}
+
+// Sort mode applied to every listing
+var sortMode = SORT_DIRS_FIRST
+
+func validSortMode(mode SortMode) bool {
+	for _, m := range sortModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func nextSortMode(mode SortMode) SortMode {
+	for i, m := range sortModes {
+		if m == mode {
+			return sortModes[(i+1)%len(sortModes)]
+		}
+	}
+	return sortModes[0]
+}
+
+func sortItems(items []Item, mode SortMode) {
+	switch mode {
+	case SORT_NAME:
+		sort.SliceStable(items, func(i, j int) bool {
+			return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+		})
+
+	case SORT_NATURAL:
+		sort.SliceStable(items, func(i, j int) bool {
+			return naturalLess(items[i].Name, items[j].Name)
+		})
+
+	case SORT_MODIFIED:
+		modified := map[string]int64{}
+		for _, item := range items {
+			if info, err := os.Stat(item.Path); err == nil {
+				modified[item.Path] = info.ModTime().UnixNano()
+			}
+		}
+		// most recent first
+		sort.SliceStable(items, func(i, j int) bool {
+			return modified[items[i].Path] > modified[items[j].Path]
+		})
+
+	case SORT_TRACK:
+		tracks := map[string]int{}
+		for _, item := range items {
+			if !item.IsDir {
+				tracks[item.Path] = trackNumber(item.Path)
+			}
+		}
+		// folders first, then tagged tracks in order, then untagged files
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i], items[j]
+			if a.IsDir != b.IsDir {
+				return a.IsDir
+			}
+			ta, tb := tracks[a.Path], tracks[b.Path]
+			if ta != tb && ta > 0 && tb > 0 {
+				return ta < tb
+			}
+			if (ta > 0) != (tb > 0) {
+				return ta > 0
+			}
+			return naturalLess(a.Name, b.Name)
+		})
+
+	default:
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i], items[j]
+			if a.IsDir != b.IsDir {
+				return a.IsDir
+			}
+			return naturalLess(a.Name, b.Name)
+		})
+	}
+}
+
+func trackNumber(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	metadata, err := tag.ReadFrom(file)
+	if err != nil {
+		return 0
+	}
+	track, _ := metadata.Track()
+	return track
+}
+
+// naturalLess compares names ignoring case and treating digit runs as
+// numbers, so "Track 2" comes before "Track 10".
+func naturalLess(a, b string) bool {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			si := i
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			sj := j
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+		if ra[i] != rb[j] {
+			return ra[i] < rb[j]
+		}
+		i++
+		j++
+	}
+	return len(ra)-i < len(rb)-j
+}
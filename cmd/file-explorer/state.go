@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Favorites and recently played tracks are listed as "favorites:" and
+// "recent:"
+const FAVORITES_PATH = "favorites:"
+const RECENT_PATH = "recent:"
+
+const (
+	FAVORITES_ROOT = "Favorites"
+	RECENT_ROOT    = "Recently played"
+)
+
+// State is what file-explorer keeps between restarts, saved as JSON.
+type State struct {
+	Favorites []Item `json:"favorites"`
+	Recent    []Item `json:"recent"`
+	// folder shown at shutdown and the folders leading to it
+	Position *Position  `json:"position,omitempty"`
+	History  []Position `json:"history,omitempty"`
+}
+
+// Where the state is saved, empty to keep it only in memory
+var statePath string
+
+// Number of tracks kept in the recently played list
+var recentLimit = 30
+
+var state State
+
+func isStatePath(path string) bool {
+	return path == FAVORITES_PATH || path == RECENT_PATH
+}
+
+func loadState(path string) error {
+	statePath = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &state)
+}
+
+// saveState writes next to a temporary file first, so a power cut never
+// leaves it half written, then makes it the current state.
+func saveState(next State) error {
+	if statePath == "" {
+		state = next
+		return nil
+	}
+	data, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0o755); err != nil {
+		return err
+	}
+	tmp := statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, statePath); err != nil {
+		return err
+	}
+	state = next
+	return nil
+}
+
+func isFavorite(path string) bool {
+	for _, item := range state.Favorites {
+		if item.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleFavorite adds the selected item to the favorites, or removes it when
+// it is already there. It reports whether the item is now a favorite.
+func (e *Explorer) ToggleFavorite() (bool, error) {
+	if len(e.Items) == 0 || e.IsRootMenu() {
+		return false, errors.New("nothing to add to favorites")
+	}
+	selected := e.Items[e.SelectedIndex]
+
+	next := state
+	next.Favorites = []Item{}
+	for _, item := range state.Favorites {
+		if item.Path != selected.Path {
+			next.Favorites = append(next.Favorites, item)
+		}
+	}
+	added := !isFavorite(selected.Path)
+	if added {
+		next.Favorites = append(next.Favorites, selected)
+	}
+	return added, saveState(next)
+}
+
+// addRecent moves a track to the top of the recently played list.
+func addRecent(item Item) error {
+	recent := []Item{item}
+	for _, other := range state.Recent {
+		if other.Path != item.Path && len(recent) < recentLimit {
+			recent = append(recent, other)
+		}
+	}
+	next := state
+	next.Recent = recent
+	return saveState(next)
+}
+
+// NewStateExplorer lists the favorites or the recently played tracks.
+// Entries whose files are gone are hidden.
+func NewStateExplorer(path string) *Explorer {
+	root, entries := FAVORITES_ROOT, state.Favorites
+	if path == RECENT_PATH {
+		root, entries = RECENT_ROOT, state.Recent
+	}
+
+	var items []Item
+	for _, item := range entries {
+		if !isLibraryPath(item.Path) {
+			if _, err := os.Stat(item.Path); err != nil {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+
+	explorer := &Explorer{
+		Root:       root,
+		CurrentDir: path,
+		SortMode:   sortMode,
+		Items:      items,
+	}
+	explorer.RefreshTimestamp()
+	return explorer
+}
+
+// recentItem names a played track after its tag title, falling back to the
+// file name.
+func recentItem(path string, title string) Item {
+	if strings.TrimSpace(title) == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return Item{IsDir: false, Name: title, Path: path}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"log"
+	"media-player/pkg/library"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Quiet time after the last file event before rescanning, so a bulk copy
+// is handled once.
+const WATCH_DEBOUNCE = 2 * time.Second
+
+// Interval between attempts to watch roots that were missing, such as an
+// unplugged USB stick.
+const WATCH_RETRY = 10 * time.Second
+
+// Watcher follows the folders of the roots with inotify, keeping the tag
+// index up to date and telling which folders changed.
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	index    *library.Index
+	onChange func(dirs []string)
+	missing  []Root
+}
+
+func NewWatcher(index *library.Index, onChange func(dirs []string)) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{fs: fsWatcher, index: index, onChange: onChange}, nil
+}
+
+// AddRoot watches the folders of root, retrying later while it is missing.
+func (w *Watcher) AddRoot(root Root) error {
+	if err := w.AddDir(root, root.Path); err != nil {
+		w.missing = append(w.missing, root)
+		return err
+	}
+	return nil
+}
+
+// AddDir watches dir and every folder below it.
+func (w *Watcher) AddDir(root Root, dir string) error {
+	visited := map[string]bool{}
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		real, err := resolveInRoot(root, dir)
+		if err != nil {
+			return err
+		}
+		if visited[real] {
+			return nil
+		}
+		visited[real] = true
+
+		if err := w.fs.Add(dir); err != nil {
+			return err
+		}
+
+		items, err := listDir(root, dir)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if item.IsDir {
+				if err := walk(item.Path); err != nil {
+					log.Printf("Unable to watch %s: %v", item.Path, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	return walk(dir)
+}
+
+func (w *Watcher) Run(done <-chan struct{}) {
+	defer w.fs.Close()
+
+	pending := map[string]bool{}
+	timer := time.NewTimer(WATCH_DEBOUNCE)
+	timer.Stop()
+	retry := time.NewTicker(WATCH_RETRY)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-done:
+			timer.Stop()
+			return
+
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			pending[filepath.Dir(event.Name)] = true
+			timer.Reset(WATCH_DEBOUNCE)
+
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Error watching files: %v", err)
+
+		case <-timer.C:
+			dirs := collapseDirs(pending)
+			pending = map[string]bool{}
+			w.rescan(dirs)
+			w.onChange(dirs)
+
+		case <-retry.C:
+			w.retryMissing()
+		}
+	}
+}
+
+// retryMissing watches the missing roots that have appeared, rescanning
+// them as changed.
+func (w *Watcher) retryMissing() {
+	var found []string
+	var missing []Root
+	for _, root := range w.missing {
+		if err := w.AddDir(root, root.Path); err != nil {
+			missing = append(missing, root)
+			continue
+		}
+		log.Printf("Watching root %s", root.Name)
+		found = append(found, root.Path)
+	}
+	w.missing = missing
+
+	if len(found) > 0 {
+		w.rescan(found)
+		w.onChange(found)
+	}
+}
+
+// rescan updates the watches and the index of the changed folders.
+func (w *Watcher) rescan(dirs []string) {
+	for _, dir := range dirs {
+		root, ok := rootFor(dir)
+		if !ok {
+			continue
+		}
+
+		if _, err := os.Stat(dir); err != nil {
+			if w.index != nil {
+				if err := w.index.Remove(dir); err != nil {
+					log.Printf("Error removing %s from index: %v", dir, err)
+				}
+			}
+			continue
+		}
+
+		if err := w.AddDir(root, dir); err != nil {
+			log.Printf("Unable to watch %s: %v", dir, err)
+		}
+
+		if w.index == nil {
+			continue
+		}
+		tracks, err := collectTracks(root, dir)
+		if err != nil {
+			log.Printf("Error scanning %s: %v", dir, err)
+			continue
+		}
+		failed, err := w.index.Sync(dir, tracks)
+		if err != nil {
+			log.Printf("Error indexing %s: %v", dir, err)
+		}
+		for _, path := range failed {
+			log.Printf("Unable to index %s", path)
+		}
+	}
+}
+
+// collapseDirs drops the folders that are below another changed folder,
+// since rescans are recursive.
+func collapseDirs(pending map[string]bool) []string {
+	var dirs []string
+	for dir := range pending {
+		covered := false
+		for other := range pending {
+			if other != dir && within(other, dir) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func rootFor(path string) (Root, bool) {
+	for _, root := range roots {
+		if insideRoot(root, path) {
+			return root, true
+		}
+	}
+	return Root{}, false
+}
@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// Debounce forwards the edges of in once their line has kept the same level
+// for period, dropping the bounces of mechanical switches. The output is
+// closed when in is.
+func Debounce(in <-chan Edge, period time.Duration) <-chan Edge {
+	out := make(chan Edge)
+	go func() {
+		defer close(out)
+
+		// last level seen and last level sent of each line
+		pending := map[int]Edge{}
+		sent := map[int]bool{}
+
+		timer := time.NewTimer(period)
+		timer.Stop()
+
+		for {
+			select {
+			case edge, ok := <-in:
+				if !ok {
+					return
+				}
+				pending[edge.Offset] = edge
+				timer.Reset(period)
+			case now := <-timer.C:
+				next := time.Duration(0)
+				for offset, edge := range pending {
+					if wait := period - now.Sub(edge.Time); wait > 0 {
+						if next == 0 || wait < next {
+							next = wait
+						}
+						continue
+					}
+					delete(pending, offset)
+					if sent[offset] == edge.Active {
+						continue
+					}
+					sent[offset] = edge.Active
+					out <- edge
+				}
+				if next > 0 {
+					timer.Reset(next)
+				}
+			}
+		}
+	}()
+	return out
+}
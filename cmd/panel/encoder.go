@@ -0,0 +1,141 @@
+package main
+
+import "time"
+
+// Encoder is a quadrature rotary encoder (jog dial) wired to two GPIO lines,
+// with an optional push switch.
+type Encoder struct {
+	PinA int `toml:"pin_a"`
+	PinB int `toml:"pin_b"`
+	// Push switch line, read like a button; nil when there is none
+	Switch *int `toml:"switch"`
+	// Keys published for each step and for the switch
+	Left  string `toml:"left"`
+	Right string `toml:"right"`
+	Press string `toml:"press"`
+	// Quarter steps between two detents of the dial, usually 4
+	StepsPerDetent int `toml:"steps_per_detent"`
+	// Turning faster than one detent per acceleration_ms sends more than
+	// one step per detent, up to max_steps
+	Acceleration int `toml:"acceleration_ms"`
+	MaxSteps     int `toml:"max_steps"`
+}
+
+// Direction of each transition of the A/B levels, indexed by the previous
+// and the current state as a<<1|b. Invalid transitions, where both lines
+// changed at once, count as nothing.
+var quadrature = [4][4]int{
+	{0, -1, 1, 0},
+	{1, 0, 0, -1},
+	{-1, 0, 0, 1},
+	{0, 1, -1, 0},
+}
+
+// Decoder turns the edges of an encoder into steps. It only needs the
+// sequence of states, so contact bounce cancels itself out and the lines
+// are not debounced.
+type Decoder struct {
+	encoder Encoder
+	emit    func(key string)
+
+	a, b       bool
+	quarters   int
+	lastDetent time.Time
+}
+
+// NewDecoder starts decoding from the current levels of the A and B lines.
+func NewDecoder(encoder Encoder, a, b bool, emit func(key string)) *Decoder {
+	encoder = encoderDefaults(encoder)
+	return &Decoder{encoder: encoder, emit: emit, a: a, b: b}
+}
+
+// Edge updates the level of one of the lines, sending the steps of a
+// detent once it is complete.
+func (d *Decoder) Edge(edge Edge) {
+	previous := d.state()
+	switch edge.Offset {
+	case d.encoder.PinA:
+		d.a = edge.Active
+	case d.encoder.PinB:
+		d.b = edge.Active
+	default:
+		return
+	}
+
+	d.quarters += quadrature[previous][d.state()]
+	switch {
+	case d.quarters >= d.encoder.StepsPerDetent:
+		d.quarters = 0
+		d.detent(d.encoder.Right, edge.Time)
+	case d.quarters <= -d.encoder.StepsPerDetent:
+		d.quarters = 0
+		d.detent(d.encoder.Left, edge.Time)
+	}
+}
+
+func (d *Decoder) state() int {
+	state := 0
+	if d.a {
+		state |= 2
+	}
+	if d.b {
+		state |= 1
+	}
+	return state
+}
+
+func (d *Decoder) detent(key string, at time.Time) {
+	steps := 1
+	if acceleration := time.Duration(d.encoder.Acceleration) * time.Millisecond; acceleration > 0 && !d.lastDetent.IsZero() {
+		if elapsed := at.Sub(d.lastDetent); elapsed > 0 {
+			steps = int(acceleration / elapsed)
+		} else {
+			steps = d.encoder.MaxSteps
+		}
+		steps = max(1, min(steps, d.encoder.MaxSteps))
+	}
+	d.lastDetent = at
+
+	for range steps {
+		d.emit(key)
+	}
+}
+
+// splitEdges sends the edges of the lines in offsets to the first channel
+// and every other edge to the second one. Both are closed when in is.
+func splitEdges(in <-chan Edge, offsets map[int]bool) (<-chan Edge, <-chan Edge) {
+	matched := make(chan Edge)
+	rest := make(chan Edge)
+	go func() {
+		defer close(matched)
+		defer close(rest)
+		for edge := range in {
+			if offsets[edge.Offset] {
+				matched <- edge
+			} else {
+				rest <- edge
+			}
+		}
+	}()
+	return matched, rest
+}
+
+// encoderDefaults fills the keys and steps left out of the configuration.
+func encoderDefaults(encoder Encoder) Encoder {
+	if encoder.Left == "" {
+		encoder.Left = "DIAL_LEFT"
+	}
+	if encoder.Right == "" {
+		encoder.Right = "DIAL_RIGHT"
+	}
+	if encoder.Press == "" {
+		encoder.Press = "DIAL_PRESS"
+	}
+	if encoder.StepsPerDetent <= 0 {
+		encoder.StepsPerDetent = 4
+	}
+	if encoder.MaxSteps <= 0 {
+		encoder.MaxSteps = 1
+	}
+	return encoder
+}
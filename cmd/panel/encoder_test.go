@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// turn feeds the A/B states, as a<<1|b, to decoder, starting at start and
+// moving on by every for each state.
+func turn(decoder *Decoder, encoder Encoder, start time.Time, every time.Duration, states ...int) {
+	a, b := false, false
+	at := start
+	for _, state := range states {
+		nextA, nextB := state&2 != 0, state&1 != 0
+		if nextA != a {
+			a = nextA
+			decoder.Edge(Edge{Offset: encoder.PinA, Active: a, Time: at})
+		}
+		if nextB != b {
+			b = nextB
+			decoder.Edge(Edge{Offset: encoder.PinB, Active: b, Time: at})
+		}
+		at = at.Add(every)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	encoder := Encoder{PinA: 5, PinB: 6, StepsPerDetent: 4, Acceleration: 100, MaxSteps: 4}
+	start := time.Unix(0, 0)
+
+	tests := []struct {
+		name   string
+		states []int
+		every  time.Duration
+		want   []string
+	}{
+		{
+			name:   "clockwise",
+			states: []int{0b10, 0b11, 0b01, 0b00},
+			every:  50 * time.Millisecond,
+			want:   []string{"DIAL_RIGHT"},
+		},
+		{
+			name:   "counter-clockwise",
+			states: []int{0b01, 0b11, 0b10, 0b00},
+			every:  50 * time.Millisecond,
+			want:   []string{"DIAL_LEFT"},
+		},
+		{
+			name:   "bounce cancels out",
+			states: []int{0b10, 0b00, 0b10, 0b11, 0b01, 0b00},
+			every:  50 * time.Millisecond,
+			want:   []string{"DIAL_RIGHT"},
+		},
+		{
+			name:   "half a detent",
+			states: []int{0b10, 0b11},
+			every:  50 * time.Millisecond,
+			want:   nil,
+		},
+		{
+			name:   "slow detents",
+			states: []int{0b10, 0b11, 0b01, 0b00, 0b10, 0b11, 0b01, 0b00},
+			every:  50 * time.Millisecond,
+			want:   []string{"DIAL_RIGHT", "DIAL_RIGHT"},
+		},
+		{
+			name:   "fast detent sends max_steps",
+			states: []int{0b10, 0b11, 0b01, 0b00, 0b10, 0b11, 0b01, 0b00},
+			every:  time.Millisecond,
+			want:   []string{"DIAL_RIGHT", "DIAL_RIGHT", "DIAL_RIGHT", "DIAL_RIGHT", "DIAL_RIGHT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			decoder := NewDecoder(encoder, false, false, func(key string) {
+				sent = append(sent, key)
+			})
+			turn(decoder, encoder, start, tt.every, tt.states...)
+			if !reflect.DeepEqual(sent, tt.want) {
+				t.Errorf("got %v, want %v", sent, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Edge is a change of an input line, after active-low inversion.
+type Edge struct {
+	Offset int
+	Active bool
+	Time   time.Time
+}
+
+// Chip is a source of line edges: a GPIO character device, or a mock for
+// running without a Pi.
+type Chip interface {
+	// Watch requests the lines as inputs and sends their edges until the
+	// chip is closed.
+	Watch(offsets []int, activeLow bool, pullUp bool) (<-chan Edge, error)
+	// Values returns the current level of the watched lines.
+	Values(offsets []int) ([]bool, error)
+	Close() error
+}
+
+// GPIO v2 character device uAPI, from linux/gpio.h
+const (
+	GPIO_V2_GET_LINE_IOCTL        = 0xc250b407
+	GPIO_V2_LINE_GET_VALUES_IOCTL = 0xc010b40e
+
+	GPIO_V2_LINE_FLAG_ACTIVE_LOW   = 1 << 1
+	GPIO_V2_LINE_FLAG_INPUT        = 1 << 2
+	GPIO_V2_LINE_FLAG_EDGE_RISING  = 1 << 4
+	GPIO_V2_LINE_FLAG_EDGE_FALLING = 1 << 5
+	GPIO_V2_LINE_FLAG_BIAS_PULL_UP = 1 << 8
+
+	GPIO_V2_LINE_EVENT_RISING_EDGE = 1
+)
+
+type gpioV2LineAttribute struct {
+	ID      uint32
+	Padding uint32
+	Value   uint64
+}
+
+type gpioV2LineConfigAttribute struct {
+	Attr gpioV2LineAttribute
+	Mask uint64
+}
+
+type gpioV2LineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	Padding  [5]uint32
+	Attrs    [10]gpioV2LineConfigAttribute
+}
+
+type gpioV2LineRequest struct {
+	Offsets         [64]uint32
+	Consumer        [32]byte
+	Config          gpioV2LineConfig
+	NumLines        uint32
+	EventBufferSize uint32
+	Padding         [5]uint32
+	Fd              int32
+}
+
+type gpioV2LineValues struct {
+	Bits uint64
+	Mask uint64
+}
+
+type gpioV2LineEvent struct {
+	Timestamp uint64
+	ID        uint32
+	Offset    uint32
+	Seqno     uint32
+	LineSeqno uint32
+	Padding   [6]uint32
+}
+
+// CdevChip reads the lines of a /dev/gpiochip* device.
+type CdevChip struct {
+	chip    *os.File
+	line    *os.File
+	offsets []int
+}
+
+func OpenChip(path string) (*CdevChip, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &CdevChip{chip: file}, nil
+}
+
+func (c *CdevChip) Watch(offsets []int, activeLow bool, pullUp bool) (<-chan Edge, error) {
+	if c.line != nil {
+		return nil, errors.New("lines already requested")
+	}
+	if len(offsets) == 0 || len(offsets) > 64 {
+		return nil, errors.New("between 1 and 64 lines can be watched")
+	}
+
+	var request gpioV2LineRequest
+	for i, offset := range offsets {
+		request.Offsets[i] = uint32(offset)
+	}
+	copy(request.Consumer[:], "media-player-panel")
+	request.NumLines = uint32(len(offsets))
+	request.Config.Flags = GPIO_V2_LINE_FLAG_INPUT | GPIO_V2_LINE_FLAG_EDGE_RISING | GPIO_V2_LINE_FLAG_EDGE_FALLING
+	if activeLow {
+		request.Config.Flags |= GPIO_V2_LINE_FLAG_ACTIVE_LOW
+	}
+	if pullUp {
+		request.Config.Flags |= GPIO_V2_LINE_FLAG_BIAS_PULL_UP
+	}
+
+	if err := ioctl(c.chip.Fd(), GPIO_V2_GET_LINE_IOCTL, unsafe.Pointer(&request)); err != nil {
+		return nil, err
+	}
+	c.line = os.NewFile(uintptr(request.Fd), "gpio-line")
+	c.offsets = offsets
+
+	edges := make(chan Edge)
+	go func() {
+		defer close(edges)
+		var event gpioV2LineEvent
+		for {
+			if err := binary.Read(c.line, binary.LittleEndian, &event); err != nil {
+				return
+			}
+			edges <- Edge{
+				Offset: int(event.Offset),
+				Active: event.ID == GPIO_V2_LINE_EVENT_RISING_EDGE,
+				Time:   time.Now(),
+			}
+		}
+	}()
+	return edges, nil
+}
+
+func (c *CdevChip) Values(offsets []int) ([]bool, error) {
+	if c.line == nil {
+		return nil, errors.New("no lines requested")
+	}
+
+	// the mask selects lines by their index in the request
+	var values gpioV2LineValues
+	index := map[int]int{}
+	for i, offset := range c.offsets {
+		index[offset] = i
+	}
+	for _, offset := range offsets {
+		i, ok := index[offset]
+		if !ok {
+			return nil, errors.New("line not requested")
+		}
+		values.Mask |= 1 << i
+	}
+
+	if err := ioctl(c.line.Fd(), GPIO_V2_LINE_GET_VALUES_IOCTL, unsafe.Pointer(&values)); err != nil {
+		return nil, err
+	}
+
+	result := make([]bool, len(offsets))
+	for n, offset := range offsets {
+		result[n] = values.Bits&(1<<index[offset]) != 0
+	}
+	return result, nil
+}
+
+func (c *CdevChip) Close() error {
+	if c.line != nil {
+		c.line.Close()
+	}
+	return c.chip.Close()
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
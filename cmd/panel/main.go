@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"media-player/pkg/config"
+	"media-player/pkg/gesture"
+	"media-player/pkg/queue"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const TOPIC = "remote-control"
+
+// Source of the keys published, told apart from the remotes
+const SOURCE = "panel"
+
+type CommandData struct {
+	Timestamp string          `json:"timestamp"`
+	Key       string          `json:"key"`
+	Gesture   gesture.Gesture `json:"gesture"`
+	Source    string          `json:"source"`
+}
+
+// Button is a switch wired to a GPIO line, published as Key.
+type Button struct {
+	Pin int    `toml:"pin"`
+	Key string `toml:"key"`
+}
+
+type Config struct {
+	// GPIO character device
+	Chip string `toml:"chip"`
+	// Time a line must keep its level to count as a press or release
+	Debounce int `toml:"debounce_ms"`
+	// Buttons that close to ground, read as pressed when low
+	ActiveLow bool `toml:"active_low"`
+	// Enables the internal pull-up resistor of the lines
+	PullUp   bool           `toml:"pull_up"`
+	Buttons  []Button       `toml:"buttons"`
+	Encoders []Encoder      `toml:"encoders"`
+	Gestures gesture.Config `toml:"gestures"`
+	// Auto-repeat of held buttons, like remote keys: first repeat after
+	// repeat_delay_ms, then every repeat_ms. Zero disables it.
+	RepeatDelay int `toml:"repeat_delay_ms"`
+	Repeat      int `toml:"repeat_ms"`
+}
+
+func main() {
+	log.SetFlags(0)
+	configPath := flag.String("config", config.Dir+"/panel.toml", "configuration file")
+	mock := flag.Bool("mock", false, "read \"<pin> <0|1>\" lines from stdin instead of GPIO")
+	flag.Parse()
+
+	cfg := Config{
+		Chip:        "/dev/gpiochip0",
+		Debounce:    20,
+		ActiveLow:   true,
+		PullUp:      true,
+		RepeatDelay: 500,
+		Repeat:      150,
+		Gestures:    gesture.Config{LongPress: 800, DoublePress: 400, HoldKeys: []string{"KEY_OK"}},
+	}
+	if err := config.Load(*configPath, &cfg); err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if len(cfg.Buttons) == 0 && len(cfg.Encoders) == 0 {
+		log.Fatalf("No buttons or encoders configured in %s", *configPath)
+	}
+
+	var chip Chip
+	if *mock {
+		mockChip := NewMockChip()
+		go mockChip.Feed(os.Stdin)
+		chip = mockChip
+	} else {
+		cdev, err := OpenChip(cfg.Chip)
+		if err != nil {
+			log.Fatalf("Error opening GPIO chip: %v", err)
+		}
+		chip = cdev
+	}
+	defer chip.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGABRT)
+	defer stop()
+
+	run(ctx, chip, cfg)
+}
+
+func run(ctx context.Context, chip Chip, cfg Config) {
+	q := queue.NewQueue()
+
+	log.Printf("Reading %d buttons and %d encoders from %s", len(cfg.Buttons), len(cfg.Encoders), cfg.Chip)
+	err := readButtons(ctx, chip, cfg, func(key string, g gesture.Gesture) {
+		sendKey(q, key, g)
+	})
+	if err != nil {
+		log.Fatalf("Error reading GPIO lines: %v", err)
+	}
+}
+
+// readButtons sends the gestures of the buttons to emit until ctx is done
+// or the lines are closed.
+func readButtons(ctx context.Context, chip Chip, cfg Config, emit func(key string, g gesture.Gesture)) error {
+	keys := map[int]string{}
+	var offsets []int
+	for _, button := range cfg.Buttons {
+		keys[button.Pin] = button.Key
+		offsets = append(offsets, button.Pin)
+	}
+
+	dialPins := map[int]bool{}
+	for _, encoder := range cfg.Encoders {
+		dialPins[encoder.PinA] = true
+		dialPins[encoder.PinB] = true
+		offsets = append(offsets, encoder.PinA, encoder.PinB)
+		if encoder.Switch != nil {
+			keys[*encoder.Switch] = encoderDefaults(encoder).Press
+			offsets = append(offsets, *encoder.Switch)
+		}
+	}
+
+	edges, err := chip.Watch(offsets, cfg.ActiveLow, cfg.PullUp)
+	if err != nil {
+		return err
+	}
+	turns, presses := splitEdges(edges, dialPins)
+	presses = Debounce(presses, time.Duration(cfg.Debounce)*time.Millisecond)
+
+	decoders := map[int]*Decoder{}
+	for _, encoder := range cfg.Encoders {
+		levels, err := chip.Values([]int{encoder.PinA, encoder.PinB})
+		if err != nil {
+			return err
+		}
+		decoder := NewDecoder(encoder, levels[0], levels[1], func(key string) {
+			emit(key, gesture.PRESS)
+		})
+		decoders[encoder.PinA] = decoder
+		decoders[encoder.PinB] = decoder
+	}
+
+	detector := gesture.NewDetector(cfg.Gestures, emit)
+
+	repeatDelay := time.Duration(cfg.RepeatDelay) * time.Millisecond
+	repeatEvery := time.Duration(cfg.Repeat) * time.Millisecond
+	var held string
+	var nextRepeat time.Time
+
+	// short period, so long presses and repeats are sent while a button is
+	// held
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping service...")
+			return nil
+		case edge, ok := <-turns:
+			if !ok {
+				turns = nil
+				continue
+			}
+			decoders[edge.Offset].Edge(edge)
+		case edge, ok := <-presses:
+			if !ok {
+				log.Println("GPIO lines closed, stopping service...")
+				return nil
+			}
+			key := keys[edge.Offset]
+			if edge.Active {
+				held, nextRepeat = key, edge.Time.Add(repeatDelay)
+				detector.Down(key, edge.Time)
+			} else {
+				if key == held {
+					held = ""
+				}
+				detector.Up(key, edge.Time)
+			}
+		case now := <-ticker.C:
+			if held != "" && repeatEvery > 0 && !now.Before(nextRepeat) {
+				nextRepeat = now.Add(repeatEvery)
+				detector.Repeat(held, now)
+				continue
+			}
+			detector.Tick(now)
+		}
+	}
+}
+
+func sendKey(q *queue.Queue, key string, g gesture.Gesture) {
+	command := CommandData{
+		Timestamp: time.Now().Format("02-01-2006T15:04:05.000"),
+		Key:       key,
+		Gesture:   g,
+		Source:    SOURCE,
+	}
+
+	data, err := json.Marshal(command)
+	if err != nil {
+		log.Printf("Error serializing command: %v", err)
+		return
+	}
+
+	if err := q.Publish(TOPIC, string(data)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", TOPIC, err)
+	}
+}
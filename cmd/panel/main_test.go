@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"media-player/pkg/gesture"
+)
+
+type published struct {
+	key     string
+	gesture gesture.Gesture
+	at      time.Time
+}
+
+var testConfig = Config{
+	Debounce:    20,
+	Buttons:     []Button{{Pin: 5, Key: "KEY_UP"}, {Pin: 6, Key: "KEY_OK"}},
+	Gestures:    gesture.Config{HoldKeys: []string{"KEY_OK"}},
+	RepeatDelay: 300,
+	Repeat:      100,
+}
+
+// startPanel reads the buttons of a mock chip, returning the chip and the
+// published keys, collected until stop is called.
+func startPanel(t *testing.T) (chip *MockChip, stop func() []published) {
+	chip = NewMockChip()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	keys := make(chan published, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := readButtons(ctx, chip, testConfig, func(key string, g gesture.Gesture) {
+			keys <- published{key, g, time.Now()}
+		})
+		if err != nil {
+			t.Errorf("readButtons: %v", err)
+		}
+	}()
+
+	// edges of lines set before Watch are not sent
+	for !chipWatching(chip) {
+		time.Sleep(time.Millisecond)
+	}
+
+	return chip, func() []published {
+		cancel()
+		<-done
+		chip.Close()
+		close(keys)
+
+		var sent []published
+		for key := range keys {
+			sent = append(sent, key)
+		}
+		return sent
+	}
+}
+
+func chipWatching(chip *MockChip) bool {
+	chip.mu.Lock()
+	defer chip.mu.Unlock()
+	return len(chip.watched) > 0
+}
+
+func gestures(sent []published) []string {
+	var names []string
+	for _, key := range sent {
+		names = append(names, key.key+":"+string(key.gesture))
+	}
+	return names
+}
+
+func TestPanelBounceDropped(t *testing.T) {
+	chip, stop := startPanel(t)
+
+	// shorter than debounce_ms
+	chip.Set(5, true)
+	time.Sleep(2 * time.Millisecond)
+	chip.Set(5, false)
+	time.Sleep(100 * time.Millisecond)
+
+	if sent := stop(); len(sent) != 0 {
+		t.Errorf("got %v, want no keys", gestures(sent))
+	}
+}
+
+func TestPanelPressRelease(t *testing.T) {
+	chip, stop := startPanel(t)
+
+	chip.Set(5, true)
+	time.Sleep(60 * time.Millisecond)
+	chip.Set(5, false)
+	time.Sleep(60 * time.Millisecond)
+	chip.Set(6, true)
+	time.Sleep(60 * time.Millisecond)
+	chip.Set(6, false)
+	time.Sleep(60 * time.Millisecond)
+
+	want := []string{"KEY_UP:press", "KEY_OK:press"}
+	if got := gestures(stop()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPanelRepeat(t *testing.T) {
+	chip, stop := startPanel(t)
+
+	start := time.Now()
+	chip.Set(5, true)
+	time.Sleep(500 * time.Millisecond)
+	chip.Set(5, false)
+	time.Sleep(60 * time.Millisecond)
+
+	sent := stop()
+	if len(sent) < 2 || sent[0].gesture != gesture.PRESS {
+		t.Fatalf("got %v, want a press followed by repeats", gestures(sent))
+	}
+	for _, key := range sent[1:] {
+		if key.gesture != gesture.REPEAT {
+			t.Errorf("got %v, want repeats after the press", gestures(sent))
+		}
+	}
+	delay := time.Duration(testConfig.RepeatDelay) * time.Millisecond
+	if first := sent[1].at.Sub(start); first < delay {
+		t.Errorf("first repeat after %v, want at least %v", first, delay)
+	}
+}
+
+func TestPanelUnwatchedLine(t *testing.T) {
+	chip, stop := startPanel(t)
+
+	chip.Set(9, true)
+	time.Sleep(60 * time.Millisecond)
+
+	if sent := stop(); len(sent) != 0 {
+		t.Errorf("got %v, want no keys", gestures(sent))
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// MockChip is a chip whose lines are set by hand, to run the panel without
+// GPIO hardware.
+type MockChip struct {
+	mu     sync.Mutex
+	levels map[int]bool
+	// lines requested by Watch, the only ones sending edges
+	watched map[int]bool
+	edges   chan Edge
+	closed  bool
+}
+
+func NewMockChip() *MockChip {
+	return &MockChip{levels: map[int]bool{}, watched: map[int]bool{}, edges: make(chan Edge, 16)}
+}
+
+func (m *MockChip) Watch(offsets []int, activeLow bool, pullUp bool) (<-chan Edge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, offset := range offsets {
+		m.watched[offset] = true
+	}
+	return m.edges, nil
+}
+
+func (m *MockChip) Values(offsets []int) ([]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := make([]bool, len(offsets))
+	for i, offset := range offsets {
+		values[i] = m.levels[offset]
+	}
+	return values, nil
+}
+
+// Set changes the level of a line, sending an edge when it changes and the
+// line is watched.
+func (m *MockChip) Set(offset int, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errors.New("chip closed")
+	}
+	if m.levels[offset] == active {
+		return nil
+	}
+	m.levels[offset] = active
+	if !m.watched[offset] {
+		return nil
+	}
+	m.edges <- Edge{Offset: offset, Active: active, Time: time.Now()}
+	return nil
+}
+
+// Feed sets lines from lines of the form "<pin> <0|1>" read from r, e.g.
+// stdin, until it ends.
+func (m *MockChip) Feed(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var offset, level int
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %d", &offset, &level); err != nil {
+			log.Printf("Expected \"<pin> <0|1>\": %v", err)
+			continue
+		}
+		if err := m.Set(offset, level != 0); err != nil {
+			return
+		}
+	}
+}
+
+func (m *MockChip) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.edges)
+	}
+	return nil
+}
@@ -6,18 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"media-player/pkg/queue"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
-
-	"github.com/dhowden/tag"
-	"github.com/faiface/beep"
-	"github.com/faiface/beep/mp3"
-	"github.com/faiface/beep/speaker"
 )
 
 const PLAYER_TOPIC = "player"
+const PLAYER_COMMAND_TOPIC = "player-command"
+const NOTIFICATION_TOPIC = "notification"
+const ACTIONS_TOPIC = "actions"
 
 // Tipos de estado e ação
 type PlayerState string
@@ -36,6 +35,15 @@ const (
 	Stop     Action = "stop"
 	Next     Action = "next"
 	Previous Action = "previous"
+	Jump     Action = "jump"
+
+	// Comandos que não são transições de estado
+	Enqueue    Action = "enqueue"
+	Toggle     Action = "toggle"
+	VolumeSet  Action = "volume"
+	VolumeUp   Action = "volume_up"
+	VolumeDown Action = "volume_down"
+	Mute       Action = "mute"
 )
 
 // Payload serializável
@@ -54,11 +62,13 @@ var transitions = map[PlayerState]map[Action]PlayerState{
 		Play:     Playing,
 		Next:     Loaded,
 		Previous: Loaded,
+		Jump:     Loaded,
 	},
 	Playing: {
 		Stop:     Loaded,
 		Next:     Loaded,
 		Previous: Loaded,
+		Jump:     Loaded,
 		Pause:    Paused,
 	},
 	Paused: {
@@ -66,33 +76,79 @@ var transitions = map[PlayerState]map[Action]PlayerState{
 		Stop:     Loaded,
 		Next:     Loaded,
 		Previous: Loaded,
+		Jump:     Loaded,
 	},
 }
 
 type Time struct {
-	Current string `json:current`
-	Total   string `json:total`
+	Current string `json:"current"`
+	Total   string `json:"total"`
 }
 
 type Data struct {
-	Title  string `json:title`
-	Artist string `json:artist`
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Path   string `json:"path"`
 }
 
 type Media struct {
-	Type  string      `json:type`
+	Type  string      `json:"type"`
 	State PlayerState `json:"state"`
-	Time  Time        `json:time`
-	Data  Data        `json:data`
+	Time  Time        `json:"time"`
+	Data  Data        `json:"data"`
 }
 
 type PlayerData struct {
-	Timestamp string `json:timestamp`
-	Media     Media  `json:media`
+	Timestamp string `json:"timestamp"`
+	Media     Media  `json:"media"`
+}
+
+// Comando recebido pelo tópico player-command
+type PlayerCommand struct {
+	Timestamp string   `json:"timestamp"`
+	Action    Action   `json:"action"`
+	Tracks    []string `json:"tracks,omitempty"`
+	Index     int      `json:"index,omitempty"`
+	// Volume em porcento, para a ação volume
+	Level int `json:"level,omitempty"`
+}
+
+// Ação do controle remoto, já traduzida pelo serviço actions
+type ActionData struct {
+	Timestamp string `json:"timestamp"`
+	Action    string `json:"action"`
+	Key       string `json:"key"`
+	Context   string `json:"context"`
+	// Número digitado no controle, a partir de 1
+	Number int `json:"number,omitempty"`
+}
+
+// Ações do tópico actions tratadas pelo player
+var playerActions = map[string]Action{
+	"player.play":     Play,
+	"player.pause":    Pause,
+	"player.toggle":   Toggle,
+	"player.stop":     Stop,
+	"player.next":     Next,
+	"player.previous": Previous,
+	"player.jump":     Jump,
+	"volume.set":      VolumeSet,
+	"volume.up":       VolumeUp,
+	"volume.down":     VolumeDown,
+	"volume.mute":     Mute,
+}
+
+// Mensagem exibida temporariamente pelo display
+type Notification struct {
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Text      string `json:"text"`
+	Level     int    `json:"level"`
 }
 
 func NewPlayerData(tp string) *PlayerData {
-	timestamp := time.Now().In(time.FixedZone("GMT-3", -3*60*60)).Format("02-01-2006T15:04:05.000")
+	timestamp := time.Now().Format("02-01-2006T15:04:05.000")
 	time := Time{
 		Current: "",
 		Total:   "",
@@ -131,10 +187,11 @@ func (data *PlayerData) UpdateCurrentTime(currentTime string) {
 	data.Media.Time.Current = currentTime
 }
 
-func (playerData *PlayerData) UpdateMediaData(artist string, title string) {
+func (playerData *PlayerData) UpdateMediaData(path string, artist string, title string) {
 	data := Data{
 		Artist: artist,
 		Title:  title,
+		Path:   path,
 	}
 	playerData.Media.Data = data
 }
@@ -152,88 +209,103 @@ func main() {
 }
 
 func run(ctx context.Context) {
-	go playMp3("/home/hallison/sample.mp3")
-	<-ctx.Done()
+	q := queue.NewQueue()
+	player := NewPlayer(q)
+	go player.Run(ctx.Done())
+
+	if err := q.Subscribe(ctx, handleCommand(player), PLAYER_COMMAND_TOPIC, ACTIONS_TOPIC); err != nil {
+		log.Printf("Error subscribing to topic: %v", err)
+	}
 	fmt.Println("Shutting down...")
 }
 
-func printPlayerDataJson(playerData PlayerData) {
-	playerDataJson, err := json.Marshal(playerData)
-	if err != nil {
-		log.Printf("Error serializing DateTime: %v", err)
+func handleCommand(player *Player) func(topic, message string) {
+	return func(topic, message string) {
+		var command PlayerCommand
+		if topic == ACTIONS_TOPIC {
+			var data ActionData
+			if err := json.Unmarshal([]byte(message), &data); err != nil {
+				log.Printf("Error parsing JSON for ActionData: %v", err)
+				return
+			}
+			action, ok := playerActions[data.Action]
+			if !ok {
+				return
+			}
+			command.Action = action
+			command.Index = data.Number - 1
+			command.Level = data.Number
+		} else if err := json.Unmarshal([]byte(message), &command); err != nil {
+			log.Printf("Error parsing JSON for PlayerCommand: %v", err)
+			return
+		}
+
+		var err error
+		switch command.Action {
+		case Load:
+			err = player.Load(command.Tracks, command.Index)
+		case Enqueue:
+			err = player.Enqueue(command.Tracks)
+		case Play:
+			err = player.Play()
+		case Pause:
+			err = player.Pause()
+		case Toggle:
+			err = player.Toggle()
+		case Stop:
+			err = player.Stop()
+		case Next:
+			err = player.Next()
+		case Previous:
+			err = player.Previous()
+		case Jump:
+			err = player.Jump(command.Index)
+		case VolumeSet:
+			err = player.SetVolume(command.Level)
+		case VolumeUp:
+			err = player.ChangeVolume(VOLUME_STEP)
+		case VolumeDown:
+			err = player.ChangeVolume(-VOLUME_STEP)
+		case Mute:
+			err = player.ToggleMute()
+		default:
+			err = fmt.Errorf("unknown action %s", command.Action)
+		}
+
+		if err != nil {
+			log.Printf("Error on %s: %v", command.Action, err)
+			notify(player.q, "error", "Invalid action", string(command.Action))
+		}
 	}
-	fmt.Printf("Payload: %v\n", string(playerDataJson))
 }
 
-func playMp3(path string) {
-	playerData := NewPlayerData("MP3")
-	printPlayerDataJson(*playerData)
-
-	file, err := os.Open(path)
+func publishPlayerData(q *queue.Queue, playerData PlayerData) {
+	playerDataJson, err := json.Marshal(playerData)
 	if err != nil {
-		fmt.Println("Fail when opening MP3 file:", err)
+		log.Printf("Error serializing PlayerData: %v", err)
 		return
 	}
-	defer file.Close()
+	fmt.Printf("Payload: %v\n", string(playerDataJson))
 
-	if err = playerData.UpdateState(Load); err != nil {
-		fmt.Println("Unable to update state:", err)
-		return
+	if err := q.Publish(PLAYER_TOPIC, string(playerDataJson)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", PLAYER_TOPIC, err)
 	}
+}
 
-	playerData.UpdateState(Load)
-	printPlayerDataJson(*playerData)
-
-	metadata, err := tag.ReadFrom(file)
-	if err != nil {
-		fmt.Println("Erro ao ler metadados:", err)
-		return
-	}
+func notify(q *queue.Queue, tp string, title string, text string) {
+	publishNotification(q, Notification{Type: tp, Title: title, Text: text})
+}
 
-	playerData.UpdateMediaData(metadata.Artist(), metadata.Title())
-	printPlayerDataJson(*playerData)
+func publishNotification(q *queue.Queue, notification Notification) {
+	notification.Timestamp = time.Now().Format("02-01-2006T15:04:05.000")
 
-	file.Seek(0, 0) // Reset pointer before decoding
-	streamer, format, err := mp3.Decode(file)
+	data, err := json.Marshal(notification)
 	if err != nil {
-		fmt.Println("Fail when decoding MP3 file:", err)
+		log.Printf("Error serializing Notification: %v", err)
 		return
 	}
-	defer streamer.Close()
 
-	speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/10))
-
-	positioner, ok := streamer.(beep.StreamSeekCloser)
-	if !ok {
-		fmt.Println("Streamer does not support seeking or position")
-		return
-	}
-	done := make(chan bool)
-
-	len := positioner.Len()
-	total := time.Duration(len) * time.Second / time.Duration(format.SampleRate)
-	playerData.Media.Time.Total = total.String()
-
-	speaker.Play(beep.Seq(streamer, beep.Callback(func() {
-		done <- true
-	})))
-	playerData.UpdateState(Play)
-
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			pos := positioner.Position()
-			elapsed := time.Duration(pos) * time.Second / time.Duration(format.SampleRate)
-			playerData.UpdateCurrentTime(elapsed.String())
-			printPlayerDataJson(*playerData)
-
-		case <-done:
-			playerData.UpdateState(Stop)
-			printPlayerDataJson(*playerData)
-			return
-		}
+	if err := q.Publish(NOTIFICATION_TOPIC, string(data)); err != nil {
+		log.Printf("Error publishing to topic %s: %v", NOTIFICATION_TOPIC, err)
 	}
 }
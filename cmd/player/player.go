@@ -0,0 +1,355 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"media-player/pkg/queue"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/speaker"
+)
+
+// Player reproduz uma lista de faixas e publica o estado a cada mudança
+type Player struct {
+	mu     sync.Mutex
+	q      *queue.Queue
+	data   *PlayerData
+	tracks []string
+	index  int
+
+	streamer   beep.StreamSeekCloser
+	ctrl       *beep.Ctrl
+	volume     *effects.Volume
+	sampleRate beep.SampleRate
+	// incrementado a cada faixa aberta, para ignorar callbacks de faixas antigas
+	generation int
+
+	// volume em porcento, mantido entre as faixas
+	level int
+	muted bool
+}
+
+func NewPlayer(q *queue.Queue) *Player {
+	return &Player{q: q, data: NewPlayerData("MP3"), level: DEFAULT_VOLUME}
+}
+
+// Load substitui a lista de faixas, posicionando na faixa index
+func (p *Player) Load(tracks []string, index int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.load(tracks, index)
+}
+
+// load é o Load com mu já travado
+func (p *Player) load(tracks []string, index int) error {
+	if len(tracks) == 0 {
+		return errors.New("no tracks to load")
+	}
+	if index < 0 || index >= len(tracks) {
+		index = 0
+	}
+
+	if err := p.unload(); err != nil {
+		return err
+	}
+	if err := p.data.UpdateState(Load); err != nil {
+		return err
+	}
+	p.tracks = tracks
+	p.index = index
+	p.publish()
+	return nil
+}
+
+// Enqueue adiciona faixas ao final da lista. Com o player parado (Idle) as
+// faixas são carregadas mas não tocadas: enfileirar nunca inicia o som,
+// quem toca é o play enviado junto com o load ao escolher uma faixa.
+func (p *Player) Enqueue(tracks []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.data.Media.State == Idle {
+		return p.load(tracks, 0)
+	}
+	p.tracks = append(p.tracks, tracks...)
+	return nil
+}
+
+func (p *Player) Play() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.data.Media.State {
+	case Paused:
+		if err := p.data.UpdateState(Play); err != nil {
+			return err
+		}
+		speaker.Lock()
+		p.ctrl.Paused = false
+		speaker.Unlock()
+		p.publish()
+		return nil
+	case Loaded:
+		if err := p.start(); err != nil {
+			return err
+		}
+		return nil
+	default:
+		return p.data.UpdateState(Play)
+	}
+}
+
+func (p *Player) Pause() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.data.UpdateState(Pause); err != nil {
+		return err
+	}
+	speaker.Lock()
+	p.ctrl.Paused = true
+	speaker.Unlock()
+	p.publish()
+	return nil
+}
+
+// Toggle alterna entre reproduzir e pausar
+func (p *Player) Toggle() error {
+	p.mu.Lock()
+	state := p.data.Media.State
+	p.mu.Unlock()
+
+	if state == Playing {
+		return p.Pause()
+	}
+	return p.Play()
+}
+
+func (p *Player) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.data.UpdateState(Stop); err != nil {
+		return err
+	}
+	p.closeTrack()
+	p.data.UpdateCurrentTime("")
+	p.publish()
+	return nil
+}
+
+func (p *Player) Next() error {
+	return p.skip(Next, 1)
+}
+
+func (p *Player) Previous() error {
+	return p.skip(Previous, -1)
+}
+
+// Jump vai para a faixa index da lista, mantendo a reprodução
+func (p *Player) Jump(index int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index < 0 || index >= len(p.tracks) {
+		return fmt.Errorf("no track %d in a list of %d", index+1, len(p.tracks))
+	}
+	return p.moveTo(Jump, index)
+}
+
+func (p *Player) skip(action Action, offset int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	index := p.index + offset
+	if index < 0 || index >= len(p.tracks) {
+		return fmt.Errorf("no track to skip to from %d", p.index)
+	}
+	return p.moveTo(action, index)
+}
+
+// moveTo troca a faixa atual por index. Deve ser chamado com mu travado.
+func (p *Player) moveTo(action Action, index int) error {
+	wasPlaying := p.data.Media.State == Playing
+	if err := p.data.UpdateState(action); err != nil {
+		return err
+	}
+	p.closeTrack()
+	p.index = index
+
+	if wasPlaying {
+		return p.start()
+	}
+	p.publish()
+	return nil
+}
+
+// Run publica o tempo de reprodução a cada segundo até ctx terminar
+func (p *Player) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			p.mu.Lock()
+			p.closeTrack()
+			p.mu.Unlock()
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			if p.data.Media.State == Playing && p.streamer != nil {
+				speaker.Lock()
+				pos := p.streamer.Position()
+				speaker.Unlock()
+				elapsed := p.sampleRate.D(pos).Round(time.Second)
+				p.data.UpdateCurrentTime(elapsed.String())
+				p.publish()
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// start abre a faixa atual e inicia a reprodução. Deve ser chamado com mu travado.
+func (p *Player) start() error {
+	if err := p.openTrack(p.tracks[p.index]); err != nil {
+		notify(p.q, "error", "Can't play", openError(err))
+		return err
+	}
+	if err := p.data.UpdateState(Play); err != nil {
+		p.closeTrack()
+		return err
+	}
+
+	p.generation++
+	generation := p.generation
+	speaker.Play(beep.Seq(p.newVolume(), beep.Callback(func() {
+		// chamado com o speaker travado
+		go p.trackEnded(generation)
+	})))
+	p.publish()
+	return nil
+}
+
+func (p *Player) trackEnded(generation int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if generation != p.generation || p.data.Media.State != Playing {
+		return
+	}
+
+	if p.index+1 >= len(p.tracks) {
+		p.data.UpdateState(Stop)
+		p.closeTrack()
+		p.data.UpdateCurrentTime("")
+		p.publish()
+		return
+	}
+
+	p.data.UpdateState(Next)
+	p.closeTrack()
+	p.index++
+	if err := p.start(); err != nil {
+		log.Printf("Error playing next track: %v", err)
+	}
+}
+
+func (p *Player) openTrack(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	artist, title := "", filepath.Base(path)
+	if metadata, err := tag.ReadFrom(file); err == nil {
+		artist = metadata.Artist()
+		if metadata.Title() != "" {
+			title = metadata.Title()
+		}
+	}
+	p.data.UpdateMediaData(path, artist, title)
+
+	file.Seek(0, 0) // Reset pointer before decoding
+	streamer, format, err := mp3.Decode(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if format.SampleRate != p.sampleRate {
+		if err := speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/10)); err != nil {
+			streamer.Close()
+			return err
+		}
+		p.sampleRate = format.SampleRate
+	}
+
+	p.streamer = streamer
+	p.ctrl = &beep.Ctrl{Streamer: streamer}
+	p.data.Media.Time.Total = format.SampleRate.D(streamer.Len()).Round(time.Second).String()
+	p.data.UpdateCurrentTime(time.Duration(0).String())
+	return nil
+}
+
+// openError descreve para o display por que a faixa não abriu
+func openError(err error) string {
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "file not found"
+	case errors.Is(err, fs.ErrPermission):
+		return "permission denied"
+	case errors.As(err, &pathErr):
+		return "can't read file"
+	default:
+		// falhas do decodificador
+		return "unsupported file"
+	}
+}
+
+func (p *Player) closeTrack() {
+	if p.streamer == nil {
+		return
+	}
+	speaker.Clear()
+	// fecha também o arquivo
+	p.streamer.Close()
+	p.streamer = nil
+	p.ctrl = nil
+	p.volume = nil
+}
+
+// unload volta ao estado inicial, parando a faixa atual se necessário
+func (p *Player) unload() error {
+	state := p.data.Media.State
+	if state == Playing || state == Paused {
+		if err := p.data.UpdateState(Stop); err != nil {
+			return err
+		}
+		p.closeTrack()
+	}
+	if p.data.Media.State == Loaded {
+		if err := p.data.UpdateState(Unload); err != nil {
+			return err
+		}
+	}
+	p.tracks = nil
+	p.index = 0
+	return nil
+}
+
+func (p *Player) publish() {
+	p.data.Timestamp = time.Now().Format("02-01-2006T15:04:05.000")
+	publishPlayerData(p.q, *p.data)
+}
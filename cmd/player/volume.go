@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/faiface/beep/effects"
+	"github.com/faiface/beep/speaker"
+)
+
+// Passo de volume.up e volume.down, em porcento
+const VOLUME_STEP = 5
+
+// Volume inicial, em porcento
+const DEFAULT_VOLUME = 80
+
+// Atenuação em potências de 2 no volume 1%; 100% toca sem alteração
+const volumeRange = 6.0
+
+// applyVolume ajusta o efeito de volume da faixa atual. Deve ser chamado com
+// mu travado.
+func (p *Player) applyVolume() {
+	if p.volume == nil {
+		return
+	}
+	speaker.Lock()
+	p.volume.Silent = p.muted || p.level == 0
+	p.volume.Volume = volumeRange * float64(p.level-100) / 100
+	speaker.Unlock()
+}
+
+// newVolume envolve o streamer da faixa com o volume atual. Deve ser
+// chamado com mu travado.
+func (p *Player) newVolume() *effects.Volume {
+	p.volume = &effects.Volume{Streamer: p.ctrl, Base: 2}
+	p.applyVolume()
+	return p.volume
+}
+
+// SetVolume muda o volume para level porcento, mostrando-o no display
+func (p *Player) SetVolume(level int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if level < 0 || level > 100 {
+		return fmt.Errorf("volume %d out of 0-100", level)
+	}
+	p.level = level
+	p.muted = false
+	p.applyVolume()
+	p.notifyVolume()
+	return nil
+}
+
+// ChangeVolume soma delta porcento ao volume, limitado a 0-100
+func (p *Player) ChangeVolume(delta int) error {
+	p.mu.Lock()
+	level := max(0, min(100, p.level+delta))
+	p.mu.Unlock()
+	return p.SetVolume(level)
+}
+
+// ToggleMute silencia ou restaura o volume
+func (p *Player) ToggleMute() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.muted = !p.muted
+	p.applyVolume()
+	p.notifyVolume()
+	return nil
+}
+
+// notifyVolume mostra o volume no display. Deve ser chamado com mu travado.
+func (p *Player) notifyVolume() {
+	notification := Notification{Type: "volume", Level: p.level}
+	if p.muted {
+		notification.Title = "Muted"
+		notification.Level = 0
+	}
+	publishNotification(p.q, notification)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const INPUT_DEVICES = "/proc/bus/input/devices"
+
+// InputDevice is an entry of /proc/bus/input/devices.
+type InputDevice struct {
+	Name string
+	Phys string
+	// event node, e.g. /dev/input/event0
+	Path string
+	// event types reported, as the EV bitmask
+	Events uint64
+}
+
+func (d InputDevice) HasKeys() bool {
+	return d.Events&(1<<EV_KEY) != 0
+}
+
+// DeviceConfig selects an input device. Name and Phys match when contained
+// in the device values; Path is used when no device matches.
+type DeviceConfig struct {
+	// Tag sent with every key of the device
+	Source string `toml:"source"`
+	Name   string `toml:"name"`
+	Phys   string `toml:"phys"`
+	Path   string `toml:"path"`
+	// Keymap file, in ir-keytable or our own format. Without it every key
+	// is published by its Linux name.
+	Keymap string `toml:"keymap"`
+	// Milliseconds in which a second press of the same key is dropped, for
+	// receivers that report a press twice. Zero keeps every press.
+	Dedup int `toml:"dedup_ms"`
+}
+
+func (c DeviceConfig) source() string {
+	switch {
+	case c.Source != "":
+		return c.Source
+	case c.Name != "":
+		return c.Name
+	}
+	return c.Path
+}
+
+func listInputDevices() ([]InputDevice, error) {
+	file, err := os.Open(INPUT_DEVICES)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var devices []InputDevice
+	var device InputDevice
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if device.Path != "" {
+				devices = append(devices, device)
+			}
+			device = InputDevice{}
+			continue
+		}
+		if len(line) < 3 {
+			continue
+		}
+
+		value := line[3:]
+		switch line[0] {
+		case 'N':
+			device.Name = strings.Trim(strings.TrimPrefix(value, "Name="), `"`)
+		case 'P':
+			device.Phys = strings.TrimPrefix(value, "Phys=")
+		case 'H':
+			for _, handler := range strings.Fields(strings.TrimPrefix(value, "Handlers=")) {
+				if strings.HasPrefix(handler, "event") {
+					device.Path = "/dev/input/" + handler
+				}
+			}
+		case 'B':
+			if bits, ok := strings.CutPrefix(value, "EV="); ok {
+				device.Events, _ = strconv.ParseUint(bits, 16, 64)
+			}
+		}
+	}
+	if device.Path != "" {
+		devices = append(devices, device)
+	}
+	return devices, scanner.Err()
+}
+
+// findDevice returns the event node of the first device with keys matching
+// config, or config.Path when none does.
+func findDevice(config DeviceConfig) (string, error) {
+	devices, err := listInputDevices()
+	if err != nil && config.Path == "" {
+		return "", err
+	}
+
+	if config.Name != "" || config.Phys != "" {
+		for _, device := range devices {
+			if !device.HasKeys() {
+				continue
+			}
+			if config.Name != "" && !strings.Contains(device.Name, config.Name) {
+				continue
+			}
+			if config.Phys != "" && !strings.Contains(device.Phys, config.Phys) {
+				continue
+			}
+			return device.Path, nil
+		}
+	}
+
+	if config.Path != "" {
+		if _, err := os.Stat(config.Path); err != nil {
+			return "", err
+		}
+		return config.Path, nil
+	}
+	return "", fmt.Errorf("no input device named %q at %q", config.Name, config.Phys)
+}
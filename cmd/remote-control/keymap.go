@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// KeymapFile is either an ir-keytable keymap, whose scancodes list the keys
+// of the remote, or our own format:
+//
+//	[keys]
+//	KEY_PLAYPAUSE = "KEY_PLAYPAUSE"
+//	KEY_DVD = "KEY_MEDIA"  # published as KEY_MEDIA
+//	148 = "KEY_PROG1"      # by Linux key code
+type KeymapFile struct {
+	Keys      map[string]string `toml:"keys"`
+	Protocols []struct {
+		Scancodes map[string]string `toml:"scancodes"`
+	} `toml:"protocols"`
+}
+
+// Keymap translates Linux key codes to the names published. Codes not in
+// the keymap are dropped.
+type Keymap struct {
+	names map[uint16]string
+}
+
+// Codes by name and names by code, built from keyCodes
+var (
+	codesByName = map[string]uint16{}
+	namesByCode = map[uint16]string{}
+)
+
+func init() {
+	for _, key := range keyCodes {
+		codesByName[key.Name] = key.Code
+		if _, ok := namesByCode[key.Code]; !ok {
+			namesByCode[key.Code] = key.Name
+		}
+	}
+}
+
+// DefaultKeymap publishes every Linux key by its name, used for devices
+// without a keymap such as keyboards.
+func DefaultKeymap() *Keymap {
+	return &Keymap{names: namesByCode}
+}
+
+func LoadKeymap(path string) (*Keymap, error) {
+	var file KeymapFile
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		return nil, err
+	}
+
+	keymap := &Keymap{names: map[uint16]string{}}
+	for key, name := range file.Keys {
+		code, err := parseKeyCode(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keymap.names[code] = name
+	}
+	for _, protocol := range file.Protocols {
+		for _, name := range protocol.Scancodes {
+			code, err := parseKeyCode(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			keymap.names[code] = name
+		}
+	}
+
+	if len(keymap.names) == 0 {
+		return nil, fmt.Errorf("%s: no keys", path)
+	}
+	return keymap, nil
+}
+
+// Name returns the name published for code, or false when it is not mapped.
+func (k *Keymap) Name(code uint16) (string, bool) {
+	name, ok := k.names[code]
+	return name, ok && name != ""
+}
+
+// parseKeyCode accepts a Linux key name, like KEY_OK, or its code.
+func parseKeyCode(key string) (uint16, error) {
+	if code, ok := codesByName[key]; ok {
+		return code, nil
+	}
+	code, err := strconv.ParseUint(key, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("unknown key %s", key)
+	}
+	return uint16(code), nil
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"flag"
 	"log"
+	"media-player/pkg/config"
+	"media-player/pkg/gesture"
 	"media-player/pkg/queue"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -15,8 +19,18 @@ import (
 const TOPIC = "remote-control"
 
 type CommandData struct {
-	Timestamp string `json:"timestamp"`
-	Key       string `json:"key"`
+	Timestamp string          `json:"timestamp"`
+	Key       string          `json:"key"`
+	Gesture   gesture.Gesture `json:"gesture"`
+	Source    string          `json:"source"`
+}
+
+type Config struct {
+	Devices []DeviceConfig `toml:"devices"`
+	// Single [device] table of older configuration files, read as the
+	// first of the devices
+	Device   *DeviceConfig  `toml:"device"`
+	Gestures gesture.Config `toml:"gestures"`
 }
 
 type InputEvent struct {
@@ -27,127 +41,170 @@ type InputEvent struct {
 	Value int32
 }
 
-type Code int
-
-const (
-	KEY_PROG1           Code = 148
-	KEY_SWITCHVIDEOMODE Code = 227
-	KEY_REFRESH         Code = 173
-	KEY_DVD             Code = 389
-	KEY_MEDIA           Code = 226
-	KEY_PAGEUP          Code = 104
-	KEY_STOP            Code = 128
-	KEY_REWIND          Code = 168
-	KEY_PLAYPAUSE       Code = 164
-	KEY_FASTFORWARD     Code = 208
-	KEY_PAGEDOWN        Code = 109
-	KEY_PREVIOUS        Code = 412
-	KEY_UP              Code = 103
-	KEY_NEXT            Code = 407
-	KEY_LEFT            Code = 105
-	KEY_OK              Code = 352
-	KEY_RIGHT           Code = 106
-	KEY_BACKSPACE       Code = 14
-	KEY_DOWN            Code = 108
-	KEY_INFO            Code = 358
-	KEY_VOLUMEDOWN      Code = 114
-	KEY_MUTE            Code = 113
-	KEY_VOLUMEUP        Code = 115
-)
+// Event type of key presses
+const EV_KEY = 1
+
+// Time between attempts to find an unplugged device
+const RETRY_INTERVAL = 2 * time.Second
 
 func main() {
 	log.SetFlags(0)
+	configPath := flag.String("config", config.Dir+"/remote-control.toml", "configuration file")
+	flag.Parse()
+
+	cfg := Config{
+		Gestures: gesture.Config{LongPress: 800, DoublePress: 400, HoldKeys: []string{"KEY_OK"}},
+	}
+	if err := config.Load(*configPath, &cfg); err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if cfg.Device != nil {
+		log.Printf("The [device] table is deprecated, use [[devices]] instead")
+		cfg.Devices = append([]DeviceConfig{*cfg.Device}, cfg.Devices...)
+	}
+	if len(cfg.Devices) == 0 {
+		cfg.Devices = []DeviceConfig{{
+			Source: "ir",
+			Name:   "gpio_ir_recv",
+			Path:   "/dev/input/event0",
+			Keymap: "/lib/udev/rc_keymaps/hp_rc1762302-00_rc6.toml",
+		}}
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(),
 		os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGABRT)
 	defer stop()
 
-	run(ctx)
+	run(ctx, cfg)
+}
+
+func run(ctx context.Context, cfg Config) {
+	q := queue.NewQueue()
+
+	var wg sync.WaitGroup
+	for _, device := range cfg.Devices {
+		keymap := DefaultKeymap()
+		if device.Keymap != "" {
+			// a missing keymap must not take the other devices down
+			loaded, err := LoadKeymap(device.Keymap)
+			if err != nil {
+				log.Printf("Error loading keymap, publishing %s keys by their Linux names: %v", device.source(), err)
+			} else {
+				keymap = loaded
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watchDevice(ctx, q, device, keymap, cfg.Gestures)
+		}()
+	}
+	wg.Wait()
+	log.Println("Stopping service...")
+}
+
+// watchDevice reads the device while it is plugged in, looking it up again
+// whenever it is lost, until ctx is done.
+func watchDevice(ctx context.Context, q *queue.Queue, device DeviceConfig, keymap *Keymap, gestures gesture.Config) {
+	source := device.source()
+	detector := gesture.NewDetector(gestures, func(key string, g gesture.Gesture) {
+		sendKey(q, key, g, source)
+	})
+
+	waiting := false
+	for {
+		path, err := findDevice(device)
+		if err == nil {
+			waiting = false
+			log.Printf("Reading %s input from %s", source, path)
+			err = readDevice(ctx, path, keymap, detector, device.Dedup)
+			if err == nil {
+				return
+			}
+			log.Printf("Input device %s lost: %v", path, err)
+			detector.Cancel()
+		} else if !waiting {
+			waiting = true
+			log.Printf("Waiting for %s input device: %v", source, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(RETRY_INTERVAL):
+		}
+	}
 }
 
-func run(ctx context.Context) {
-	input, err := os.Open("/dev/input/event0")
+// readDevice feeds the key events of path to detector until ctx is done,
+// returning nil, or the device fails, e.g. when unplugged. Keys missing
+// from keymap are dropped, and a key pressed again within dedup
+// milliseconds is taken as a duplicate.
+func readDevice(ctx context.Context, path string, keymap *Keymap, detector *gesture.Detector, dedup int) error {
+	input, err := os.Open(path)
 	if err != nil {
-		log.Fatalf("Error openning connection with IR input: %v", err)
+		return err
 	}
 	defer input.Close()
-	q := queue.NewQueue()
+
+	window := time.Duration(dedup) * time.Millisecond
+	var lastKey string
+	var lastDown time.Time
 
 	var event InputEvent
 	for {
-		input.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		// short deadline, so long presses are detected while the key is held
+		input.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
 		err := binary.Read(input, binary.LittleEndian, &event)
-		var key string
 		select {
 		case <-ctx.Done():
-			log.Println("Stopping service...")
-			return
+			return nil
 		default:
-			if err != nil {
-				if os.IsTimeout(err) {
-					continue
-				}
-				log.Fatalf("Error reading IR input: %v", err)
+		}
+
+		now := time.Now()
+		if err != nil {
+			if os.IsTimeout(err) {
+				detector.Tick(now)
+				continue
+			}
+			return err
+		}
+		if event.Type != EV_KEY {
+			detector.Tick(now)
+			continue
+		}
+
+		key, ok := keymap.Name(event.Code)
+		if !ok {
+			if event.Value == 1 {
+				log.Printf("Dropping unmapped key code %d from %s", event.Code, path)
 			}
-			if event.Value == 1 { //key down
-				switch event.Code {
-				case uint16(KEY_PROG1):
-					key = "KEY_PROG1"
-				case uint16(KEY_SWITCHVIDEOMODE):
-					key = "KEY_SWITCHVIDEOMODE"
-				case uint16(KEY_REFRESH):
-					key = "KEY_REFRESH"
-				case uint16(KEY_DVD):
-					key = "KEY_DVD"
-				case uint16(KEY_MEDIA):
-					key = "KEY_MEDIA"
-				case uint16(KEY_PAGEUP):
-					key = "KEY_PAGEUP"
-				case uint16(KEY_STOP):
-					key = "KEY_STOP"
-				case uint16(KEY_REWIND):
-					key = "KEY_REWIND"
-				case uint16(KEY_PLAYPAUSE):
-					key = "KEY_PLAYPAUSE"
-				case uint16(KEY_FASTFORWARD):
-					key = "KEY_FASTFORWARD"
-				case uint16(KEY_PAGEDOWN):
-					key = "KEY_PAGEDOWN"
-				case uint16(KEY_PREVIOUS):
-					key = "KEY_PREVIOUS"
-				case uint16(KEY_UP):
-					key = "KEY_UP"
-				case uint16(KEY_NEXT):
-					key = "KEY_NEXT"
-				case uint16(KEY_LEFT):
-					key = "KEY_LEFT"
-				case uint16(KEY_OK):
-					key = "KEY_OK"
-				case uint16(KEY_RIGHT):
-					key = "KEY_RIGHT"
-				case uint16(KEY_BACKSPACE):
-					key = "KEY_BACKSPACE"
-				case uint16(KEY_DOWN):
-					key = "KEY_DOWN"
-				case uint16(KEY_INFO):
-					key = "KEY_INFO"
-				case uint16(KEY_VOLUMEDOWN):
-					key = "KEY_VOLUMEDOWN"
-				case uint16(KEY_MUTE):
-					key = "KEY_MUTE"
-				case uint16(KEY_VOLUMEUP):
-					key = "KEY_VOLUMEUP"
-				}
-				sendKey(&key, q)
+			continue
+		}
+
+		switch event.Value {
+		case 1: // key down
+			if key == lastKey && now.Sub(lastDown) < window {
+				continue
 			}
+			lastKey, lastDown = key, now
+			detector.Down(key, now)
+		case 2: // auto-repeat
+			detector.Repeat(key, now)
+		case 0: // key up
+			detector.Up(key, now)
 		}
 	}
 }
 
-func sendKey(key *string, q *queue.Queue) {
+func sendKey(q *queue.Queue, key string, g gesture.Gesture, source string) {
 	command := CommandData{
 		Timestamp: time.Now().Format("02-01-2006T15:04:05.000"),
-		Key:       *key,
+		Key:       key,
+		Gesture:   g,
+		Source:    source,
 	}
 
 	data, err := json.Marshal(command)
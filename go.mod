@@ -2,22 +2,28 @@ module media-player
 
 go 1.24.2
 
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/d2r2/go-hd44780 v0.0.0-20181002113701-74cc28c83a3e
+	github.com/d2r2/go-i2c v0.0.0-20191123181816-73a8a799d6bc
+	github.com/d2r2/go-logger v0.0.0-20210606094344-60e9d1233e22
+	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8
+	github.com/faiface/beep v1.1.0
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/redis/go-redis/v9 v9.7.3
+	go.etcd.io/bbolt v1.4.0
+	golang.org/x/sys v0.29.0
+)
+
 require (
 	github.com/cespare/xxhash/v2 v2.2.0 // indirect
-	github.com/d2r2/go-hd44780 v0.0.0-20181002113701-74cc28c83a3e // indirect
-	github.com/d2r2/go-i2c v0.0.0-20191123181816-73a8a799d6bc // indirect
-	github.com/d2r2/go-logger v0.0.0-20210606094344-60e9d1233e22 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8 // indirect
-	github.com/faiface/beep v1.1.0 // indirect
 	github.com/hajimehoshi/go-mp3 v0.3.0 // indirect
 	github.com/hajimehoshi/oto v1.0.1 // indirect
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	github.com/redis/go-redis/v9 v9.7.3 // indirect
 	golang.org/x/exp v0.0.0-20190306152737-a1d7652674e8 // indirect
 	golang.org/x/image v0.0.0-20190227222117-0694c2d4d067 // indirect
 	golang.org/x/mobile v0.0.0-20190415191353-3e0bab5405d6 // indirect
-	golang.org/x/sys v0.0.0-20190626150813-e07cf5db2756 // indirect
 )
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/BurntSushi/toml"
+)
+
+const Dir = "/etc/media-player"
+
+// Load decodes the TOML file at path into cfg. Fields missing from the file
+// keep the values already set in cfg, and a missing file is not an error,
+// so every service can run with its built-in defaults.
+func Load(path string, cfg any) error {
+	_, err := toml.DecodeFile(path, cfg)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
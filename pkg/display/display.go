@@ -49,14 +49,10 @@ func (d *Display) TurnBacklightOff() error {
 	return d.lcd.BacklightOff()
 }
 
+// ShowText writes the message bytes as they are, so custom characters (0-7)
+// and characters from the upper half of the LCD ROM can be used.
 func (d *Display) ShowText(message string, line int, align Alignment) error {
-	var deviceLine device.ShowOptions
-	switch line {
-	case 1:
-		deviceLine = device.SHOW_LINE_1
-	case 2:
-		deviceLine = device.SHOW_LINE_2
-	default:
+	if line < 1 || line > 2 {
 		return errors.New("invalid line number")
 	}
 
@@ -77,7 +73,27 @@ func (d *Display) ShowText(message string, line int, align Alignment) error {
 		// left side as fallback
 	}
 
-	return d.lcd.ShowMessage(message, deviceLine)
+	if err := d.lcd.SetPosition(line-1, 0); err != nil {
+		return err
+	}
+	_, err := d.lcd.Write([]byte(message))
+	return err
+}
+
+// CreateChar stores a 5x8 pattern, one byte per row, in the CGRAM slot
+// location (0-7). The character is shown by writing the byte location.
+func (d *Display) CreateChar(location int, pattern [8]byte) error {
+	if location < 0 || location > 7 {
+		return errors.New("invalid character location")
+	}
+	if err := d.lcd.Command(device.CMD_CGRAM_Set | byte(location<<3)); err != nil {
+		return err
+	}
+	if _, err := d.lcd.Write(pattern[:]); err != nil {
+		return err
+	}
+	// Back to DDRAM, otherwise the next writes would go to CGRAM
+	return d.lcd.SetPosition(0, 0)
 }
 
 func spaces(n int) string {
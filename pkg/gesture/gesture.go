@@ -0,0 +1,171 @@
+package gesture
+
+import "time"
+
+type Gesture string
+
+const (
+	PRESS  Gesture = "press"
+	LONG   Gesture = "long"
+	REPEAT Gesture = "repeat"
+	DOUBLE Gesture = "double"
+)
+
+type Config struct {
+	// Time a key must be held to count as a long press
+	LongPress int `toml:"long_press_ms"`
+	// Maximum time between two presses of a key to count as a double press
+	DoublePress int `toml:"double_press_ms"`
+	// Keys whose press is only sent on release, so a long press does not
+	// trigger the short one too. Other keys are sent as soon as pressed.
+	HoldKeys []string `toml:"hold_keys"`
+	// Keys whose press is held back for the double press time, so a double
+	// press does not trigger the single one too. Other keys send a press
+	// for each of the two presses, followed by the double.
+	DoubleKeys []string `toml:"double_keys"`
+}
+
+// Detector turns key down, repeat and up events into gestures:
+//   - press: sent on key down, or on release for hold keys released before
+//     the long press time
+//   - long: sent once when the key has been held for the long press time
+//   - repeat: sent for every auto-repeat of a held key; hold keys only
+//     repeat after their long press
+//   - double: sent after the press when the same key was pressed shortly
+//     before; double keys send either the press or the double, the press
+//     once the double press time has passed
+type Detector struct {
+	longPress   time.Duration
+	doublePress time.Duration
+	holdKeys    map[string]bool
+	doubleKeys  map[string]bool
+	emit        func(key string, gesture Gesture)
+
+	held     string
+	heldAt   time.Time
+	longSent bool
+	// last press of each key, for double press detection
+	lastPress map[string]time.Time
+	// double key whose press waits for a possible second press
+	pending   string
+	pendingAt time.Time
+}
+
+func NewDetector(config Config, emit func(key string, gesture Gesture)) *Detector {
+	detector := &Detector{
+		longPress:   time.Duration(config.LongPress) * time.Millisecond,
+		doublePress: time.Duration(config.DoublePress) * time.Millisecond,
+		holdKeys:    map[string]bool{},
+		doubleKeys:  map[string]bool{},
+		emit:        emit,
+		lastPress:   map[string]time.Time{},
+	}
+	for _, key := range config.HoldKeys {
+		detector.holdKeys[key] = true
+	}
+	for _, key := range config.DoubleKeys {
+		detector.doubleKeys[key] = true
+	}
+	return detector
+}
+
+func (d *Detector) Down(key string, at time.Time) {
+	// a key down without the release of the previous key
+	if d.held != "" && d.held != key {
+		d.Up(d.held, at)
+	}
+	if d.pending != key {
+		d.sendPending()
+	}
+
+	d.held = key
+	d.heldAt = at
+	d.longSent = false
+
+	if !d.holdKeys[key] {
+		d.press(key, at)
+	}
+}
+
+func (d *Detector) Repeat(key string, at time.Time) {
+	if d.held != key {
+		d.Down(key, at)
+		return
+	}
+	d.Tick(at)
+	if d.holdKeys[key] && !d.longSent {
+		return
+	}
+	d.emit(key, REPEAT)
+}
+
+func (d *Detector) Up(key string, at time.Time) {
+	if d.held != key {
+		return
+	}
+	d.Tick(at)
+	if d.holdKeys[key] && !d.longSent {
+		d.press(key, at)
+	}
+	d.held = ""
+}
+
+// Cancel forgets the held key without sending anything, used when the
+// device is gone before the key was released. A press waiting for a double
+// press is complete, so it is sent.
+func (d *Detector) Cancel() {
+	d.held = ""
+	d.sendPending()
+}
+
+// Tick sends the long press of the held key once its time is reached, and
+// the press of a double key once no second press came. It must be called
+// periodically, since the key sends nothing while held before auto-repeat
+// starts.
+func (d *Detector) Tick(at time.Time) {
+	if d.pending != "" && at.Sub(d.pendingAt) > d.doublePress {
+		d.sendPending()
+	}
+	if d.held == "" || d.longSent || d.longPress <= 0 {
+		return
+	}
+	if at.Sub(d.heldAt) >= d.longPress {
+		d.longSent = true
+		d.emit(d.held, LONG)
+	}
+}
+
+func (d *Detector) press(key string, at time.Time) {
+	if d.doubleKeys[key] && d.doublePress > 0 {
+		if d.pending == key && at.Sub(d.pendingAt) <= d.doublePress {
+			d.pending = ""
+			d.emit(key, DOUBLE)
+			return
+		}
+		d.sendPending()
+		d.pending = key
+		d.pendingAt = at
+		return
+	}
+
+	d.emit(key, PRESS)
+
+	last, ok := d.lastPress[key]
+	if ok && d.doublePress > 0 && at.Sub(last) <= d.doublePress {
+		d.emit(key, DOUBLE)
+		// a third press starts over
+		delete(d.lastPress, key)
+		return
+	}
+	d.lastPress[key] = at
+}
+
+// sendPending sends the press held back for a double key.
+func (d *Detector) sendPending() {
+	if d.pending == "" {
+		return
+	}
+	key := d.pending
+	d.pending = ""
+	d.emit(key, PRESS)
+}
@@ -0,0 +1,159 @@
+package gesture
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type recorded struct {
+	key     string
+	gesture Gesture
+}
+
+func newTestDetector(config Config) (*Detector, *[]recorded) {
+	var sent []recorded
+	detector := NewDetector(config, func(key string, gesture Gesture) {
+		sent = append(sent, recorded{key, gesture})
+	})
+	return detector, &sent
+}
+
+var testConfig = Config{
+	LongPress:   800,
+	DoublePress: 400,
+	HoldKeys:    []string{"KEY_OK"},
+	DoubleKeys:  []string{"KEY_INFO"},
+}
+
+func ms(n int) time.Time {
+	return time.Unix(0, 0).Add(time.Duration(n) * time.Millisecond)
+}
+
+func TestDetector(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps func(d *Detector)
+		want  []recorded
+	}{
+		{
+			name: "press",
+			steps: func(d *Detector) {
+				d.Down("KEY_UP", ms(0))
+				d.Up("KEY_UP", ms(100))
+				d.Tick(ms(1000))
+			},
+			want: []recorded{{"KEY_UP", PRESS}},
+		},
+		{
+			name: "repeat",
+			steps: func(d *Detector) {
+				d.Down("KEY_UP", ms(0))
+				d.Repeat("KEY_UP", ms(250))
+				d.Repeat("KEY_UP", ms(300))
+				d.Up("KEY_UP", ms(320))
+			},
+			want: []recorded{
+				{"KEY_UP", PRESS},
+				{"KEY_UP", REPEAT},
+				{"KEY_UP", REPEAT},
+			},
+		},
+		{
+			name: "hold key short press is sent on release",
+			steps: func(d *Detector) {
+				d.Down("KEY_OK", ms(0))
+				d.Repeat("KEY_OK", ms(250))
+				d.Up("KEY_OK", ms(300))
+			},
+			want: []recorded{{"KEY_OK", PRESS}},
+		},
+		{
+			name: "hold key long press",
+			steps: func(d *Detector) {
+				d.Down("KEY_OK", ms(0))
+				d.Tick(ms(500))
+				d.Tick(ms(850))
+				d.Repeat("KEY_OK", ms(900))
+				d.Up("KEY_OK", ms(950))
+			},
+			want: []recorded{
+				{"KEY_OK", LONG},
+				{"KEY_OK", REPEAT},
+			},
+		},
+		{
+			name: "double press sends both presses",
+			steps: func(d *Detector) {
+				d.Down("KEY_UP", ms(0))
+				d.Up("KEY_UP", ms(50))
+				d.Down("KEY_UP", ms(200))
+				d.Up("KEY_UP", ms(250))
+			},
+			want: []recorded{
+				{"KEY_UP", PRESS},
+				{"KEY_UP", PRESS},
+				{"KEY_UP", DOUBLE},
+			},
+		},
+		{
+			name: "presses too far apart",
+			steps: func(d *Detector) {
+				d.Down("KEY_UP", ms(0))
+				d.Up("KEY_UP", ms(50))
+				d.Down("KEY_UP", ms(500))
+				d.Up("KEY_UP", ms(550))
+			},
+			want: []recorded{
+				{"KEY_UP", PRESS},
+				{"KEY_UP", PRESS},
+			},
+		},
+		{
+			name: "double key double press",
+			steps: func(d *Detector) {
+				d.Down("KEY_INFO", ms(0))
+				d.Up("KEY_INFO", ms(50))
+				d.Tick(ms(100))
+				d.Down("KEY_INFO", ms(200))
+				d.Up("KEY_INFO", ms(250))
+				d.Tick(ms(1000))
+			},
+			want: []recorded{{"KEY_INFO", DOUBLE}},
+		},
+		{
+			name: "double key press waits for the double press time",
+			steps: func(d *Detector) {
+				d.Down("KEY_INFO", ms(0))
+				d.Up("KEY_INFO", ms(50))
+				d.Tick(ms(400))
+				d.Tick(ms(450))
+				d.Tick(ms(500))
+			},
+			want: []recorded{{"KEY_INFO", PRESS}},
+		},
+		{
+			name: "another key sends the waiting press first",
+			steps: func(d *Detector) {
+				d.Down("KEY_INFO", ms(0))
+				d.Up("KEY_INFO", ms(50))
+				d.Down("KEY_UP", ms(100))
+				d.Up("KEY_UP", ms(150))
+			},
+			want: []recorded{
+				{"KEY_INFO", PRESS},
+				{"KEY_UP", PRESS},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector, sent := newTestDetector(testConfig)
+			tt.steps(detector)
+			if !reflect.DeepEqual(*sent, tt.want) {
+				t.Errorf("got %v, want %v", *sent, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package library
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dhowden/tag"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	UnknownArtist = "Unknown Artist"
+	UnknownAlbum  = "Unknown Album"
+	UnknownGenre  = "Unknown Genre"
+	UnknownYear   = "Unknown Year"
+)
+
+var tracksBucket = []byte("tracks")
+
+type Track struct {
+	Path    string `json:"path"`
+	Title   string `json:"title"`
+	Artist  string `json:"artist"`
+	Album   string `json:"album"`
+	Genre   string `json:"genre"`
+	Year    int    `json:"year"`
+	Number  int    `json:"number"`
+	ModTime int64  `json:"mod_time"`
+}
+
+// YearName is the year as shown in the browse views.
+func (t Track) YearName() string {
+	if t.Year == 0 {
+		return UnknownYear
+	}
+	return strconv.Itoa(t.Year)
+}
+
+// Index keeps the tags of every track in a bbolt file. The tracks are also
+// kept in memory, so browsing never touches the disk.
+type Index struct {
+	mu     sync.RWMutex
+	db     *bolt.DB
+	tracks map[string]Track
+}
+
+func Open(path string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0o644, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &Index{db: db, tracks: map[string]Track{}}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(tracksBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var track Track
+			if err := json.Unmarshal(v, &track); err != nil {
+				return err
+			}
+			index.tracks[track.Path] = track
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return index, nil
+}
+
+func (i *Index) Close() error {
+	return i.db.Close()
+}
+
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.tracks)
+}
+
+// Tracks stored per transaction by Sync, so a large scan does not sync the
+// database to disk once per file
+const syncBatch = 256
+
+// Update reads the tags of path and stores them, unless the file did not
+// change since it was indexed.
+func (i *Index) Update(path string) error {
+	track, changed, err := i.read(path)
+	if err != nil || !changed {
+		return err
+	}
+	return i.store(map[string]Track{path: track})
+}
+
+// read returns the track of path and whether it changed since it was
+// indexed. The tags are only read for changed files.
+func (i *Index) read(path string) (Track, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Track{}, false, err
+	}
+
+	i.mu.RLock()
+	current, ok := i.tracks[path]
+	i.mu.RUnlock()
+	if ok && current.ModTime == info.ModTime().UnixNano() {
+		return current, false, nil
+	}
+
+	track, err := readTrack(path)
+	if err != nil {
+		return Track{}, false, err
+	}
+	track.ModTime = info.ModTime().UnixNano()
+	return track, true, nil
+}
+
+// store saves tracks in a single transaction.
+func (i *Index) store(tracks map[string]Track) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	err := i.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tracksBucket)
+		for path, track := range tracks {
+			data, err := json.Marshal(track)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(path), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for path, track := range tracks {
+		i.tracks[path] = track
+	}
+	return nil
+}
+
+// Remove deletes path and, when it is a folder, every track below it.
+func (i *Index) Remove(path string) error {
+	i.mu.RLock()
+	var removed []string
+	for p := range i.tracks {
+		if p == path || strings.HasPrefix(p, path+string(filepath.Separator)) {
+			removed = append(removed, p)
+		}
+	}
+	i.mu.RUnlock()
+
+	return i.delete(removed)
+}
+
+// delete removes the tracks at paths in a single transaction.
+func (i *Index) delete(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	err := i.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tracksBucket)
+		for _, p := range paths {
+			if err := bucket.Delete([]byte(p)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, p := range paths {
+		delete(i.tracks, p)
+	}
+	return nil
+}
+
+// Sync makes the tracks below dir match paths, indexing new or changed files
+// and removing the ones that are gone. Files that fail are skipped and
+// returned.
+func (i *Index) Sync(dir string, paths []string) (failed []string, err error) {
+	present := map[string]bool{}
+	changed := map[string]Track{}
+	for _, path := range paths {
+		present[path] = true
+		track, ok, err := i.read(path)
+		if err != nil {
+			failed = append(failed, path)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		changed[path] = track
+		if len(changed) >= syncBatch {
+			if err := i.store(changed); err != nil {
+				return failed, err
+			}
+			changed = map[string]Track{}
+		}
+	}
+	if err := i.store(changed); err != nil {
+		return failed, err
+	}
+
+	i.mu.RLock()
+	var gone []string
+	for p := range i.tracks {
+		if strings.HasPrefix(p, dir+string(filepath.Separator)) && !present[p] {
+			gone = append(gone, p)
+		}
+	}
+	i.mu.RUnlock()
+
+	return failed, i.delete(gone)
+}
+
+// Find returns the tracks accepted by filter, ordered by artist, album,
+// track number and title.
+func (i *Index) Find(filter func(Track) bool) []Track {
+	i.mu.RLock()
+	var tracks []Track
+	for _, track := range i.tracks {
+		if filter == nil || filter(track) {
+			tracks = append(tracks, track)
+		}
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(tracks, func(a, b int) bool {
+		ta, tb := tracks[a], tracks[b]
+		if ta.Artist != tb.Artist {
+			return strings.ToLower(ta.Artist) < strings.ToLower(tb.Artist)
+		}
+		if ta.Album != tb.Album {
+			return strings.ToLower(ta.Album) < strings.ToLower(tb.Album)
+		}
+		if ta.Number != tb.Number {
+			return ta.Number < tb.Number
+		}
+		return strings.ToLower(ta.Title) < strings.ToLower(tb.Title)
+	})
+	return tracks
+}
+
+// Values returns the distinct, sorted values of field among the tracks
+// accepted by filter.
+func (i *Index) Values(field func(Track) string, filter func(Track) bool) []string {
+	seen := map[string]bool{}
+	var values []string
+	for _, track := range i.Find(filter) {
+		value := field(track)
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(a, b int) bool {
+		return strings.ToLower(values[a]) < strings.ToLower(values[b])
+	})
+	return values
+}
+
+func readTrack(path string) (Track, error) {
+	track := Track{
+		Path:   path,
+		Title:  strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Artist: UnknownArtist,
+		Album:  UnknownAlbum,
+		Genre:  UnknownGenre,
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return track, err
+	}
+	defer file.Close()
+
+	metadata, err := tag.ReadFrom(file)
+	if err != nil {
+		// files without tags are still listed by their names
+		return track, nil
+	}
+
+	if title := strings.TrimSpace(metadata.Title()); title != "" {
+		track.Title = title
+	}
+	if artist := strings.TrimSpace(metadata.Artist()); artist != "" {
+		track.Artist = artist
+	}
+	if album := strings.TrimSpace(metadata.Album()); album != "" {
+		track.Album = album
+	}
+	if genre := strings.TrimSpace(metadata.Genre()); genre != "" {
+		track.Genre = genre
+	}
+	track.Year = metadata.Year()
+	track.Number, _ = metadata.Track()
+	return track, nil
+}
@@ -24,6 +24,11 @@ func (q *Queue) Publish(channel, message string) error {
 	return q.client.Publish(ctx, channel, message).Err()
 }
 
+// Ping checks that Redis can be reached.
+func (q *Queue) Ping() error {
+	return q.client.Ping(ctx).Err()
+}
+
 func (q *Queue) Subscribe(ctx context.Context, handler func(channel, message string), channels ...string) error {
 	sub := q.client.Subscribe(ctx, channels...)
 
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	EV_KEY   = 1
+	EV_MSC   = 4
+	MSC_SCAN = 4
+)
+
+type inputEvent struct {
+	Sec   uint64
+	Usec  uint64
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Teclas pedidas ao usuário, na ordem em que são aprendidas
+var steps = []struct {
+	Prompt string
+	Key    string
+}{
+	{"UP", "KEY_UP"},
+	{"DOWN", "KEY_DOWN"},
+	{"LEFT", "KEY_LEFT"},
+	{"RIGHT", "KEY_RIGHT"},
+	{"OK", "KEY_OK"},
+	{"BACK", "KEY_BACKSPACE"},
+	{"PAGE UP", "KEY_PAGEUP"},
+	{"PAGE DOWN", "KEY_PAGEDOWN"},
+	{"PLAY/PAUSE", "KEY_PLAYPAUSE"},
+	{"STOP", "KEY_STOP"},
+	{"NEXT", "KEY_NEXT"},
+	{"PREVIOUS", "KEY_PREVIOUS"},
+	{"REWIND", "KEY_REWIND"},
+	{"FAST FORWARD", "KEY_FASTFORWARD"},
+	{"VOLUME UP", "KEY_VOLUMEUP"},
+	{"VOLUME DOWN", "KEY_VOLUMEDOWN"},
+	{"MUTE", "KEY_MUTE"},
+	{"INFO (enqueue)", "KEY_INFO"},
+	{"MEDIA (sort)", "KEY_MEDIA"},
+	{"FAVORITE", "KEY_PROG1"},
+	{"MACRO 1 (DVD)", "KEY_DVD"},
+	{"MACRO 2 (video mode)", "KEY_SWITCHVIDEOMODE"},
+	{"MACRO 3 (refresh)", "KEY_REFRESH"},
+	{"1", "KEY_1"},
+	{"2", "KEY_2"},
+	{"3", "KEY_3"},
+	{"4", "KEY_4"},
+	{"5", "KEY_5"},
+	{"6", "KEY_6"},
+	{"7", "KEY_7"},
+	{"8", "KEY_8"},
+	{"9", "KEY_9"},
+	{"0", "KEY_0"},
+}
+
+// Tempo sem nenhuma tecla até pular o passo
+const skipAfter = 10 * time.Second
+
+// Tempo após cada código em que novos códigos ainda contam para a mesma
+// tecla, já que protocolos como o RC6 alternam entre dois scancodes a cada
+// toque. Recomeça a cada código, para esperar o segundo toque.
+const collectFor = 1500 * time.Millisecond
+
+func main() {
+	device := flag.String("device", "/dev/input/event0", "input device")
+	output := flag.String("out", "keymap.toml", "keymap file written")
+	scancodes := flag.Bool("scancodes", false, "record EV_MSC scancodes and write an ir-keytable keymap")
+	name := flag.String("name", "learned", "remote name, for -scancodes")
+	protocol := flag.String("protocol", "rc6", "IR protocol, for -scancodes")
+	flag.Parse()
+
+	f, err := os.Open(*device)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	learned := map[string][]uint32{}
+	owner := map[uint32]string{}
+
+	fmt.Println("Press each key twice, then wait for the next prompt. Wait 10 seconds to skip a key.")
+	for _, step := range steps {
+		for {
+			fmt.Printf("Press %s... ", step.Prompt)
+			codes := learn(f, *scancodes)
+			if len(codes) == 0 {
+				fmt.Println("skipped")
+				break
+			}
+
+			taken := ""
+			for _, code := range codes {
+				if key, ok := owner[code]; ok {
+					taken = key
+				}
+			}
+			if taken != "" {
+				fmt.Printf("already used by %s, try again\n", taken)
+				continue
+			}
+
+			for _, code := range codes {
+				owner[code] = step.Key
+			}
+			learned[step.Key] = codes
+			fmt.Println(formatCodes(codes, *scancodes))
+			break
+		}
+	}
+
+	var b strings.Builder
+	if *scancodes {
+		fmt.Fprintf(&b, "[[protocols]]\nname = %q\nprotocol = %q\nvariant = %q\n\n", *name, *protocol, *protocol)
+		b.WriteString("[protocols.scancodes]\n")
+		for _, step := range steps {
+			for _, code := range learned[step.Key] {
+				fmt.Fprintf(&b, "0x%x = %q\n", code, step.Key)
+			}
+		}
+	} else {
+		b.WriteString("# Linux key code = name published by remote-control\n[keys]\n")
+		for _, step := range steps {
+			for _, code := range learned[step.Key] {
+				fmt.Fprintf(&b, "%d = %q\n", code, step.Key)
+			}
+		}
+	}
+
+	if err := os.WriteFile(*output, []byte(b.String()), 0o644); err != nil {
+		panic(err)
+	}
+	fmt.Printf("Keymap written to %s\n", *output)
+}
+
+// learn devolve os códigos distintos de uma tecla: key codes de EV_KEY ou,
+// com scancodes, os valores de EV_MSC/MSC_SCAN.
+func learn(f *os.File, scancodes bool) []uint32 {
+	var codes []uint32
+	deadline := time.Now().Add(skipAfter)
+
+	var ev inputEvent
+	for time.Now().Before(deadline) {
+		f.SetReadDeadline(deadline)
+		if err := binary.Read(f, binary.LittleEndian, &ev); err != nil {
+			if os.IsTimeout(err) {
+				break
+			}
+			panic(err)
+		}
+
+		var code uint32
+		switch {
+		case scancodes && ev.Type == EV_MSC && ev.Code == MSC_SCAN:
+			code = uint32(ev.Value)
+		case !scancodes && ev.Type == EV_KEY && ev.Value == 1:
+			code = uint32(ev.Code)
+		default:
+			continue
+		}
+
+		deadline = time.Now().Add(collectFor)
+		if !contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+func formatCodes(codes []uint32, scancodes bool) string {
+	var parts []string
+	for _, code := range codes {
+		if scancodes {
+			parts = append(parts, fmt.Sprintf("0x%x", code))
+		} else {
+			parts = append(parts, fmt.Sprint(code))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func contains(codes []uint32, code uint32) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+# Navigate back to project path
+cd ../../
+
+# Create a new user exclusively for this service
+sudo useradd -r -s /usr/sbin/nologin actions-service
+
+# Copy the binary file to the linux binaries path
+cd cmd/actions
+go build
+sudo cp actions /usr/local/bin/actions
+
+# Navigate back to project path
+cd ../../
+
+# Copy the service file to the systemd service files
+sudo cp systemd/actions/actions.service /etc/systemd/system/actions.service
+
+# Navigate back to project path
+cd ../../
+
+# Enable and start the new service
+sudo systemctl daemon-reexec
+sudo systemctl daemon-reload
+sudo systemctl enable actions.service
+sudo systemctl start actions.service
+
+# Check status
+sudo systemctl status actions.service
\ No newline at end of file
@@ -0,0 +1,28 @@
+# Navigate back to project path
+cd ../../
+
+# Create a new user exclusively for this service
+sudo useradd -r -s /usr/sbin/nologin -G gpio panel-service
+
+# Copy the binary file to the linux binaries path
+cd cmd/panel
+go build
+sudo cp panel /usr/local/bin/panel
+
+# Navigate back to project path
+cd ../../
+
+# Copy the service file to the systemd service files
+sudo cp systemd/panel/panel.service /etc/systemd/system/panel.service
+
+# Navigate back to project path
+cd ../../
+
+# Enable and start the new service
+sudo systemctl daemon-reexec
+sudo systemctl daemon-reload
+sudo systemctl enable panel.service
+sudo systemctl start panel.service
+
+# Check status
+sudo systemctl status panel.service
\ No newline at end of file